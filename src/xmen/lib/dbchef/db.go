@@ -1,11 +1,18 @@
 package dbchef
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"reflect"
 	"sync"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -15,6 +22,62 @@ var (
 
 type DBSession struct {
 	conn *gorm.DB
+	ctx  context.Context
+}
+
+// QueryEvent describes a finished database operation
+type QueryEvent struct {
+	Context   context.Context
+	Operation string
+	Model     string
+	Rows      int64
+	Start     time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// QueryObserver is notified after every database operation
+type QueryObserver func(QueryEvent)
+
+var queryObservers []QueryObserver
+
+// AddQueryObserver registers an observer notified after every database operation
+func AddQueryObserver(observer QueryObserver) {
+	queryObservers = append(queryObservers, observer)
+}
+
+func (s *DBSession) observe(operation string, record interface{}, start time.Time, rows int64, err error) {
+	if len(queryObservers) == 0 {
+		return
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event := QueryEvent{
+		Context:   ctx,
+		Operation: operation,
+		Model:     modelName(record),
+		Rows:      rows,
+		Start:     start,
+		Duration:  time.Since(start),
+		Err:       err,
+	}
+	for _, observer := range queryObservers {
+		observer(event)
+	}
+}
+
+// modelName returns the name of the struct behind record, e.g. "Person" for *models.Person
+func modelName(record interface{}) string {
+	t := reflect.TypeOf(record)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
 }
 
 // NewDBSession initializes a singleton DB connection
@@ -29,35 +92,126 @@ func NewDBSession(connStr string) *DBSession {
 	return &DBSession{conn: db}
 }
 
-// SeedTables seeds the database with initial data for the provided models
+type transactionKey struct{}
+
+// ContextWithTransaction returns a copy of ctx that makes WithContext hand out tx, so every query made
+// on behalf of the request joins the transaction
+func ContextWithTransaction(ctx context.Context, tx *DBSession) context.Context {
+	return context.WithValue(ctx, transactionKey{}, tx)
+}
+
+// WithContext returns a session whose operations are bound to ctx, inside the transaction ctx carries if any
+func (s *DBSession) WithContext(ctx context.Context) *DBSession {
+	if tx, ok := ctx.Value(transactionKey{}).(*DBSession); ok {
+		return &DBSession{conn: tx.conn.WithContext(ctx), ctx: ctx}
+	}
+	return &DBSession{conn: s.conn.WithContext(ctx), ctx: ctx}
+}
+
+// Unscoped returns a session whose reads also see soft-deleted records
+func (s *DBSession) Unscoped() *DBSession {
+	return &DBSession{conn: s.conn.Unscoped(), ctx: s.ctx}
+}
+
+// PoolStats returns the connection pool statistics of the underlying database
+func (s *DBSession) PoolStats() (sql.DBStats, error) {
+	sqlDB, err := s.conn.DB()
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+	return sqlDB.Stats(), nil
+}
+
+// Ping checks that the database is reachable
+func (s *DBSession) Ping() error {
+	sqlDB, err := s.conn.DB()
+	if err != nil {
+		return err
+	}
+	if s.ctx != nil {
+		return sqlDB.PingContext(s.ctx)
+	}
+	return sqlDB.Ping()
+}
+
+// SeedTables seeds the database with initial data for the provided models, tables created by an older
+// version of a model get the columns added since
 func (s *DBSession) SeedTables(models []interface{}) error {
 	for _, model := range models {
 		if !s.conn.Migrator().HasTable(model) {
+			start := time.Now()
 			err := s.conn.Migrator().CreateTable(model)
+			s.observe("seed", model, start, 0, err)
 			if err != nil {
 				return err
 			}
+			continue
+		}
+		if err := s.addMissingColumns(model); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// CreateRecords inserts multiple records into the database
-func (s *DBSession) CreateRecord(record interface{}) error {
-	result := s.conn.Model(record).Create(record)
-	if result.Error != nil {
-		return result.Error
+func (s *DBSession) addMissingColumns(model interface{}) error {
+	stmt := &gorm.Statement{DB: s.conn}
+	if err := stmt.Parse(model); err != nil {
+		return err
 	}
-	// Check if the record was created successfully
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	for _, column := range stmt.Schema.DBNames {
+		if s.conn.Migrator().HasColumn(model, column) {
+			continue
+		}
+		start := time.Now()
+		err := s.conn.Migrator().AddColumn(model, column)
+		s.observe("add_column", model, start, 0, err)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// CreateRecords inserts multiple records into the database
+func (s *DBSession) CreateRecord(record interface{}) error {
+	return s.mutate("create", record, func(s *DBSession) error {
+		start := time.Now()
+		result := s.conn.Model(record).Create(record)
+		s.observe("create", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Check if the record was created successfully
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// CreateRecords inserts a slice of records, records must be a pointer to the slice
+func (s *DBSession) CreateRecords(records interface{}, batchSize int) error {
+	return s.mutateMany(records, func(s *DBSession) error {
+		start := time.Now()
+		result := s.conn.CreateInBatches(records, batchSize)
+		s.observe("create_batch", records, start, result.RowsAffected, result.Error)
+		return result.Error
+	})
+}
+
+// Transaction runs fn inside a database transaction, committing when fn returns nil and rolling back otherwise
+func (s *DBSession) Transaction(fn func(tx *DBSession) error) error {
+	return s.conn.Transaction(func(tx *gorm.DB) error {
+		return fn(&DBSession{conn: tx, ctx: s.ctx})
+	})
+}
+
 // ReadRecords retrieves records from the database based on the provided conditions
 func (s *DBSession) ReadRecord(conditions map[string]interface{}, record interface{}) error {
+	start := time.Now()
 	result := s.conn.Model(record).Find(record, conditions)
+	s.observe("read", record, start, result.RowsAffected, result.Error)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -68,28 +222,309 @@ func (s *DBSession) ReadRecord(conditions map[string]interface{}, record interfa
 	return nil
 }
 
-// UpdateRecords updates records in the database based on the provided conditions
-func (s *DBSession) UpdateRecord(record interface{}) error {
-	result := s.conn.Model(record).Updates(record)
+// ReadRecordForUpdate is ReadRecord holding a row lock until the surrounding transaction ends
+func (s *DBSession) ReadRecordForUpdate(conditions map[string]interface{}, record interface{}) error {
+	start := time.Now()
+	result := s.conn.Model(record).Clauses(clause.Locking{Strength: "UPDATE"}).Find(record, conditions)
+	s.observe("read_for_update", record, start, result.RowsAffected, result.Error)
 	if result.Error != nil {
 		return result.Error
 	}
-	// Check if any records were updated
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
 	return nil
 }
 
-// DeleteRecords deletes records from the database based on the provided conditions
-func (s *DBSession) DeleteRecord(record interface{}) error {
-	result := s.conn.Model(record).Delete(record)
+// ReadFirstRecord reads the first record matching conditions sorted by orderBy, e.g. "version DESC"
+func (s *DBSession) ReadFirstRecord(conditions map[string]interface{}, orderBy string, record interface{}) error {
+	return s.readFirst(where(s.conn.Model(record), conditions), orderBy, record)
+}
+
+// ReadFirstRecordUntil is ReadFirstRecord among the records whose column holds a time not after until
+func (s *DBSession) ReadFirstRecordUntil(conditions map[string]interface{}, column string, until time.Time, orderBy string, record interface{}) error {
+	query := where(s.conn.Model(record), conditions).Where(clause.Lte{Column: clause.Column{Name: column}, Value: until})
+	return s.readFirst(query, orderBy, record)
+}
+
+func (s *DBSession) readFirst(query *gorm.DB, orderBy string, record interface{}) error {
+	start := time.Now()
+	result := query.Order(orderBy).Limit(1).Find(record)
+	s.observe("read_first", record, start, result.RowsAffected, result.Error)
 	if result.Error != nil {
 		return result.Error
 	}
-	// Check if any records were deleted
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
 	return nil
 }
+
+// ListRecords retrieves up to limit records matching conditions ordered by id, starting after afterID
+func (s *DBSession) ListRecords(conditions map[string]interface{}, afterID string, limit int, records interface{}) error {
+	start := time.Now()
+	query := s.conn.Model(records).Order("id").Limit(limit)
+	query = where(query, conditions)
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+	result := query.Find(records)
+	s.observe("list", records, start, result.RowsAffected, result.Error)
+	return result.Error
+}
+
+// ListRecordsAfter retrieves up to limit records matching conditions ordered by column, starting after the value after
+func (s *DBSession) ListRecordsAfter(conditions map[string]interface{}, column string, after interface{}, limit int, records interface{}) error {
+	start := time.Now()
+	query := s.conn.Model(records).
+		Where(clause.Gt{Column: clause.Column{Name: column}, Value: after}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: column}}).
+		Limit(limit)
+	query = where(query, conditions)
+	result := query.Find(records)
+	s.observe("list_after", records, start, result.RowsAffected, result.Error)
+	return result.Error
+}
+
+// ListRecordsUntil retrieves up to limit records matching conditions whose column holds a time not after until,
+// ordered by column
+func (s *DBSession) ListRecordsUntil(conditions map[string]interface{}, column string, until time.Time, limit int, records interface{}) error {
+	start := time.Now()
+	query := s.conn.Model(records).
+		Where(clause.Lte{Column: clause.Column{Name: column}, Value: until}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: column}}).
+		Limit(limit)
+	query = where(query, conditions)
+	result := query.Find(records)
+	s.observe("list_until", records, start, result.RowsAffected, result.Error)
+	return result.Error
+}
+
+// FindRecords retrieves every record matching conditions sorted by orderBy, e.g. "timestamp, id"
+func (s *DBSession) FindRecords(conditions map[string]interface{}, orderBy string, records interface{}) error {
+	start := time.Now()
+	query := s.conn.Model(records).Order(orderBy)
+	query = where(query, conditions)
+	result := query.Find(records)
+	s.observe("find", records, start, result.RowsAffected, result.Error)
+	return result.Error
+}
+
+// StreamRecords reads the records matching conditions sorted by orderBy through a single server-side cursor,
+// scanning each row into record before calling fn, so the result set is never held in memory
+func (s *DBSession) StreamRecords(conditions map[string]interface{}, orderBy string, record interface{}, fn func() error) error {
+	start := time.Now()
+	var count int64
+	err := s.streamRecords(conditions, orderBy, record, func() error {
+		count++
+		return fn()
+	})
+	s.observe("stream", record, start, count, err)
+	return err
+}
+
+func (s *DBSession) streamRecords(conditions map[string]interface{}, orderBy string, record interface{}, fn func() error) error {
+	query := s.conn.Model(record).Order(orderBy)
+	query = where(query, conditions)
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	target := reflect.ValueOf(record).Elem()
+	for rows.Next() {
+		target.Set(reflect.Zero(target.Type()))
+		if err := s.conn.ScanRows(rows, record); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// UpdateRecords updates records in the database based on the provided conditions
+func (s *DBSession) UpdateRecord(record interface{}) error {
+	return s.mutate("update", record, func(s *DBSession) error {
+		start := time.Now()
+		result := s.conn.Model(record).Updates(record)
+		s.observe("update", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Check if any records were updated
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// UpdateRecordColumns is UpdateRecord writing only the named columns, zero values included
+func (s *DBSession) UpdateRecordColumns(record interface{}, columns ...string) error {
+	return s.mutate("update", record, func(s *DBSession) error {
+		start := time.Now()
+		result := s.conn.Model(record).Select(columns).Updates(record)
+		s.observe("update", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteRecords deletes records from the database based on the provided conditions
+func (s *DBSession) DeleteRecord(record interface{}) error {
+	return s.mutate("delete", record, func(s *DBSession) error {
+		start := time.Now()
+		var result *gorm.DB
+		if columns := s.stampColumns(record); columns != nil && s.softDeletes(record) {
+			// A soft delete is an update, it records who made it like any other
+			columns["deleted_at"] = time.Now()
+			result = s.conn.Model(record).Updates(columns)
+		} else {
+			result = s.conn.Model(record).Delete(record)
+		}
+		s.observe("delete", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Check if any records were deleted
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// UpdateRecordsWhere sets values on every record of model matching conditions without running the mutation
+// hooks, it returns how many were changed
+func (s *DBSession) UpdateRecordsWhere(model interface{}, conditions map[string]interface{}, values map[string]interface{}) (int64, error) {
+	start := time.Now()
+	result := where(s.conn.Model(model), conditions).Updates(values)
+	s.observe("update_where", model, start, result.RowsAffected, result.Error)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteRecordsBefore deletes the records of model whose column holds a time before cutoff, it returns how many went
+func (s *DBSession) DeleteRecordsBefore(model interface{}, column string, cutoff time.Time) (int64, error) {
+	start := time.Now()
+	result := s.conn.Where(clause.Lt{Column: clause.Column{Name: column}, Value: cutoff}).Delete(model)
+	s.observe("delete_before", model, start, result.RowsAffected, result.Error)
+	return result.RowsAffected, result.Error
+}
+
+// Records with a gorm.DeletedAt field named deleted_at are soft-deleted by DeleteRecord and hidden from reads,
+// the methods below reach them
+
+const purgeBatchSize = 500
+
+// ListDeletedRecords is ListRecords over the soft-deleted records only
+func (s *DBSession) ListDeletedRecords(conditions map[string]interface{}, afterID string, limit int, records interface{}) error {
+	start := time.Now()
+	query := s.conn.Unscoped().Model(records).Where("deleted_at IS NOT NULL").Order("id").Limit(limit)
+	query = where(query, conditions)
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+	result := query.Find(records)
+	s.observe("list_deleted", records, start, result.RowsAffected, result.Error)
+	return result.Error
+}
+
+// RestoreRecord brings a soft-deleted record back
+func (s *DBSession) RestoreRecord(record interface{}) error {
+	return s.mutate("restore", record, func(s *DBSession) error {
+		start := time.Now()
+		columns := s.stampColumns(record)
+		if columns == nil {
+			columns = map[string]interface{}{}
+		}
+		columns["deleted_at"] = nil
+		result := s.conn.Unscoped().Model(record).Where("deleted_at IS NOT NULL").Updates(columns)
+		s.observe("restore", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// PurgeRecord deletes a record for good, whether it was soft-deleted or not
+func (s *DBSession) PurgeRecord(record interface{}) error {
+	return s.mutate("purge", record, func(s *DBSession) error {
+		start := time.Now()
+		result := s.conn.Unscoped().Model(record).Delete(record)
+		s.observe("purge", record, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// PurgeFailure is a record PurgeDeletedBefore could not purge, the records after it are purged regardless
+type PurgeFailure struct {
+	Model string
+	Key   interface{}
+	Err   error
+}
+
+func (f *PurgeFailure) Error() string {
+	return fmt.Sprintf("purging %s %v: %v", f.Model, f.Key, f.Err)
+}
+
+func (f *PurgeFailure) Unwrap() error {
+	return f.Err
+}
+
+// PurgeDeletedBefore purges the records of model soft-deleted before cutoff, one by one so mutation hooks see each.
+// A record that cannot be purged is skipped rather than stalling the rest, the returned error joins a
+// PurgeFailure for each of them.
+func (s *DBSession) PurgeDeletedBefore(model interface{}, cutoff time.Time) (int64, error) {
+	var purged int64
+	var failures []error
+	var after interface{}
+	for {
+		batch := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+		query := s.conn.Unscoped().Model(model).Where("deleted_at < ?", cutoff)
+		if after != nil {
+			// Page by id so the records skipped so far are not selected again
+			query = query.Where("id > ?", after)
+		}
+		start := time.Now()
+		result := query.Order("id").Limit(purgeBatchSize).Find(batch.Interface())
+		s.observe("list_deleted", model, start, result.RowsAffected, result.Error)
+		if result.Error != nil {
+			return purged, errors.Join(append(failures, result.Error)...)
+		}
+		records := batch.Elem()
+		for i := 0; i < records.Len(); i++ {
+			record := records.Index(i).Addr().Interface()
+			key, err := s.primaryKey(record)
+			if err != nil {
+				return purged, errors.Join(append(failures, err)...)
+			}
+			after = key
+			if err := s.PurgeRecord(record); err != nil {
+				failures = append(failures, &PurgeFailure{Model: modelName(record), Key: key, Err: err})
+				continue
+			}
+			purged++
+		}
+		if records.Len() < purgeBatchSize {
+			return purged, errors.Join(failures...)
+		}
+	}
+}
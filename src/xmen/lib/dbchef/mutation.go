@@ -0,0 +1,147 @@
+package dbchef
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Mutation describes a change to one record. Before is nil for creates and After is nil for deletes,
+// otherwise both hold the full record as stored.
+type Mutation struct {
+	Context   context.Context
+	Operation string
+	Model     string
+	Key       interface{}
+	Before    interface{}
+	After     interface{}
+}
+
+// MutationHook runs inside the transaction of the change it describes, returning an error rolls the change back
+type MutationHook func(tx *DBSession, mutation Mutation) error
+
+var mutationHooks = map[string][]MutationHook{}
+
+// AddMutationHook registers a hook run for every create, update and delete of records shaped like model
+func AddMutationHook(model interface{}, hook MutationHook) {
+	name := modelName(model)
+	mutationHooks[name] = append(mutationHooks[name], hook)
+}
+
+// mutate stamps and applies a change to record, wrapping it in a transaction with its hooks when model has any
+func (s *DBSession) mutate(operation string, record interface{}, apply func(s *DBSession) error) error {
+	if operation != "purge" {
+		s.stamp(operation, record)
+	}
+	hooks := mutationHooks[modelName(record)]
+	if len(hooks) == 0 {
+		return apply(s)
+	}
+	return s.Transaction(func(tx *DBSession) error {
+		var before interface{}
+		if operation != "create" {
+			snapshot, err := tx.snapshot(record)
+			if err != nil {
+				return err
+			}
+			before = snapshot
+		}
+		if err := apply(tx); err != nil {
+			return err
+		}
+		var after interface{}
+		if operation != "delete" && operation != "purge" {
+			snapshot, err := tx.snapshot(record)
+			if err != nil {
+				return err
+			}
+			after = snapshot
+		}
+		return tx.runHooks(hooks, operation, record, before, after)
+	})
+}
+
+// mutateMany is mutate for a slice of freshly created records
+func (s *DBSession) mutateMany(records interface{}, apply func(s *DBSession) error) error {
+	s.stamp("create", records)
+	hooks := mutationHooks[modelName(records)]
+	if len(hooks) == 0 {
+		return apply(s)
+	}
+	return s.Transaction(func(tx *DBSession) error {
+		if err := apply(tx); err != nil {
+			return err
+		}
+		list := reflect.Indirect(reflect.ValueOf(records))
+		for i := 0; i < list.Len(); i++ {
+			record := list.Index(i).Addr().Interface()
+			if err := tx.runHooks(hooks, "create", record, nil, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *DBSession) runHooks(hooks []MutationHook, operation string, record, before, after interface{}) error {
+	key, err := s.primaryKey(record)
+	if err != nil {
+		return err
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mutation := Mutation{
+		Context:   ctx,
+		Operation: operation,
+		Model:     modelName(record),
+		Key:       key,
+		Before:    before,
+		After:     after,
+	}
+	for _, hook := range hooks {
+		if err := hook(s, mutation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot reads the stored version of record into a new value of the same type, soft-deleted or not
+func (s *DBSession) snapshot(record interface{}) (interface{}, error) {
+	key, err := s.primaryKey(record)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &gorm.Statement{DB: s.conn}
+	if err := stmt.Parse(record); err != nil {
+		return nil, err
+	}
+	stored := reflect.New(reflect.Indirect(reflect.ValueOf(record)).Type()).Interface()
+	column := stmt.Schema.PrioritizedPrimaryField.DBName
+	err = s.conn.Unscoped().Where(clause.Eq{Column: clause.Column{Name: column}, Value: key}).Take(stored).Error
+	return stored, err
+}
+
+var errNoPrimaryKey = errors.New("record has no primary key")
+
+func (s *DBSession) primaryKey(record interface{}) (interface{}, error) {
+	stmt := &gorm.Statement{DB: s.conn}
+	if err := stmt.Parse(record); err != nil {
+		return nil, err
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil, errNoPrimaryKey
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	value, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(record)))
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package dbchef
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stamped is implemented by records that keep who created and last changed them, and when
+type Stamped interface {
+	Stamp(operation string, actor string, at time.Time)
+}
+
+var actorOf = func(ctx context.Context) string { return "" }
+
+// SetActorResolver tells dbchef how to name the principal a write is made for from the context of the session
+func SetActorResolver(resolver func(ctx context.Context) string) {
+	actorOf = resolver
+}
+
+// stamp lets a Stamped record, or every Stamped record of a slice, note the write about to be applied
+func (s *DBSession) stamp(operation string, record interface{}) {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	actor := actorOf(ctx)
+	// Postgres keeps microseconds, stamping more would not survive the round trip
+	at := time.Now().UTC().Truncate(time.Microsecond)
+
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if value.Kind() != reflect.Slice {
+		if stamped, ok := record.(Stamped); ok {
+			stamped.Stamp(operation, actor, at)
+		}
+		return
+	}
+	for i := 0; i < value.Len(); i++ {
+		if stamped, ok := value.Index(i).Addr().Interface().(Stamped); ok {
+			stamped.Stamp(operation, actor, at)
+		}
+	}
+}
+
+// stampColumns returns the updated_at and updated_by values a Stamped record was given by stamp, keyed by
+// column, for the writes that do not save the whole record. It returns nil for other records.
+func (s *DBSession) stampColumns(record interface{}) map[string]interface{} {
+	if _, ok := record.(Stamped); !ok {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: s.conn}
+	if err := stmt.Parse(record); err != nil {
+		return nil
+	}
+	value := reflect.Indirect(reflect.ValueOf(record))
+	columns := map[string]interface{}{}
+	for _, name := range []string{"UpdatedAt", "UpdatedBy"} {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			columns[field.DBName] = value.FieldByIndex(field.StructField.Index).Interface()
+		}
+	}
+	return columns
+}
+
+// softDeletes reports whether record has a gorm.DeletedAt column, deleting it then only hides it
+func (s *DBSession) softDeletes(record interface{}) bool {
+	stmt := &gorm.Statement{DB: s.conn}
+	if err := stmt.Parse(record); err != nil {
+		return false
+	}
+	field := stmt.Schema.LookUpField("DeletedAt")
+	return field != nil && field.FieldType == reflect.TypeOf(gorm.DeletedAt{})
+}
+
+// Since is a condition value matching the records whose column holds a time at or after it
+type Since time.Time
+
+// where applies conditions to query, values are matched for equality unless they are a Since
+func where(query *gorm.DB, conditions map[string]interface{}) *gorm.DB {
+	equal := map[string]interface{}{}
+	for column, value := range conditions {
+		if since, ok := value.(Since); ok {
+			query = query.Where(clause.Gte{Column: clause.Column{Name: column}, Value: time.Time(since)})
+			continue
+		}
+		equal[column] = value
+	}
+	if len(equal) > 0 {
+		query = query.Where(equal)
+	}
+	return query
+}
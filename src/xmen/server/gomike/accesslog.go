@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	xRouter "gomike/router"
+)
+
+const (
+	accessLogJSON     = "json"
+	accessLogCommon   = "common"
+	accessLogCombined = "combined"
+)
+
+type accessLogConfig struct {
+	format     string
+	sample2xx  float64
+	out        io.Writer
+	jsonLogger *slog.Logger
+}
+
+// accessLogConfigFromEnv reads GOMIKE_ACCESS_LOG_FORMAT (json, common or combined) and
+// GOMIKE_ACCESS_LOG_SAMPLE_2XX, the fraction of successful requests to log (0 to 1)
+func accessLogConfigFromEnv(out io.Writer) (accessLogConfig, error) {
+	cfg := accessLogConfig{format: accessLogJSON, sample2xx: 1, out: out}
+	if format := os.Getenv("GOMIKE_ACCESS_LOG_FORMAT"); format != "" {
+		switch format {
+		case accessLogJSON, accessLogCommon, accessLogCombined:
+			cfg.format = format
+		default:
+			return cfg, fmt.Errorf("unsupported access log format %q", format)
+		}
+	}
+	if sample := os.Getenv("GOMIKE_ACCESS_LOG_SAMPLE_2XX"); sample != "" {
+		rate, err := strconv.ParseFloat(sample, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return cfg, fmt.Errorf("invalid access log sample rate %q, expected a number between 0 and 1", sample)
+		}
+		cfg.sample2xx = rate
+	}
+	cfg.jsonLogger = slog.New(slog.NewJSONHandler(out, nil))
+	return cfg, nil
+}
+
+func handleWithAccessLog(cfg accessLogConfig, nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := newResponseRecorder(w)
+			nextHandler.ServeHTTP(recorder, req)
+			duration := time.Since(start)
+
+			if recorder.status/100 == 2 && cfg.sample2xx < 1 && rand.Float64() >= cfg.sample2xx {
+				return
+			}
+
+			switch cfg.format {
+			case accessLogCommon, accessLogCombined:
+				writeCommonLogLine(cfg, req, recorder, start)
+			default:
+				cfg.jsonLogger.Info("access",
+					slog.String("request-id", xRouter.RequestID(req.Context())),
+					slog.String("method", req.Method),
+					slog.String("route", req.Pattern),
+					slog.String("url", req.URL.String()),
+					slog.String("proto", req.Proto),
+					slog.Int("status", recorder.status),
+					slog.Int("bytes", recorder.bytesWritten),
+					slog.Float64("duration-ms", float64(duration.Microseconds())/1000),
+					slog.String("principal", recorder.principal),
+					slog.String("remote-addr", remoteHost(req.RemoteAddr)),
+					slog.String("user-agent", req.UserAgent()),
+				)
+			}
+		},
+	)
+}
+
+// writeCommonLogLine emits the NCSA Common Log Format, extended with referer and user agent for Combined
+func writeCommonLogLine(cfg accessLogConfig, req *http.Request, recorder *responseRecorder, start time.Time) {
+	principal := recorder.principal
+	if principal == "" {
+		principal = "-"
+	}
+	size := "-"
+	if recorder.bytesWritten > 0 {
+		size = strconv.Itoa(recorder.bytesWritten)
+	}
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s",
+		remoteHost(req.RemoteAddr),
+		principal,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		req.Method+" "+req.URL.RequestURI()+" "+req.Proto,
+		recorder.status,
+		size,
+	)
+	if cfg.format == accessLogCombined {
+		line += fmt.Sprintf(" %q %q", req.Referer(), req.UserAgent())
+	}
+	fmt.Fprintln(cfg.out, line)
+}
+
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,87 @@
+// Audit trail of every change made to persons and animals, whichever API made it
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+// Track records an audit entry under resource for every create, update and delete of records shaped like model
+func Track(resource string, model any) {
+	xDb.AddMutationHook(model, func(tx *xDb.DBSession, mutation xDb.Mutation) error {
+		changes, err := Diff(mutation.Before, mutation.After)
+		if err != nil {
+			return err
+		}
+		// Saving a record unchanged is not worth an entry
+		if len(changes) == 0 {
+			return nil
+		}
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		entry := xModels.AuditEntry{
+			ID:        uuid.New().String(),
+			Resource:  resource,
+			RecordID:  fmt.Sprint(mutation.Key),
+			Operation: mutation.Operation,
+			Actor:     xRouter.Principal(mutation.Context),
+			RequestID: xRouter.RequestID(mutation.Context),
+			// Postgres keeps microseconds, hashing more would not survive the round trip
+			Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+			Changes:   string(encoded),
+			// Linked into the chain by Seal once the change commits, the write does not wait for the head
+			Pending: true,
+		}
+		return tx.CreateRecord(&entry)
+	})
+}
+
+// Diff lists the fields whose value differs between before and after, keyed by their JSON name.
+// A nil before or after stands for a record that does not exist, so every field is listed.
+func Diff(before, after any) (map[string]xModels.FieldChange, error) {
+	beforeFields, err := fieldValues(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fieldValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]xModels.FieldChange{}
+	for name, value := range afterFields {
+		previous, existed := beforeFields[name]
+		if !existed || !reflect.DeepEqual(previous, value) {
+			changes[name] = xModels.FieldChange{Before: previous, After: value}
+		}
+	}
+	for name, previous := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changes[name] = xModels.FieldChange{Before: previous}
+		}
+	}
+	return changes, nil
+}
+
+func fieldValues(record any) (map[string]any, error) {
+	fields := map[string]any{}
+	if record == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(data, &fields)
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+const (
+	headID = 1
+	// sealBatchSize bounds the entries linked under one hold of the head lock
+	sealBatchSize = 500
+	// SealInterval is how often pending entries are linked, the change feed trails the writes by about as much
+	SealInterval = 200 * time.Millisecond
+)
+
+// EnsureHead creates the row pointing at the newest audit entry, the chain starts empty
+func EnsureHead(dbSession *xDb.DBSession) error {
+	head := xModels.AuditHead{}
+	err := dbSession.ReadRecord(map[string]interface{}{"id": headID}, &head)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(strings.ToLower(err.Error()), "record not found") {
+		return err
+	}
+	return dbSession.CreateRecord(&xModels.AuditHead{ID: headID})
+}
+
+// Seal links the pending audit entries into the chain, oldest first, and returns how many it sealed. Changes
+// write their entries unchained, so only Seal takes the head row lock and only for one batch at a time.
+// Entries are numbered in the order Seal finds them committed, a later entry never gets a lower Seq.
+func Seal(dbSession *xDb.DBSession) (int, error) {
+	sealed := 0
+	for {
+		batch := 0
+		err := dbSession.Transaction(func(tx *xDb.DBSession) error {
+			head := xModels.AuditHead{}
+			if err := tx.ReadRecordForUpdate(map[string]interface{}{"id": headID}, &head); err != nil {
+				return fmt.Errorf("audit chain head: %w", err)
+			}
+			entries := []xModels.AuditEntry{}
+			if err := tx.ListRecordsAfter(map[string]interface{}{"pending": true}, "timestamp", time.Time{}, sealBatchSize, &entries); err != nil {
+				return err
+			}
+			for i := range entries {
+				entry := &entries[i]
+				entry.Seq = head.Seq + 1
+				entry.PrevHash = head.Hash
+				entry.Hash = EntryHash(*entry)
+				entry.Pending = false
+				if err := tx.UpdateRecordColumns(entry, "seq", "prev_hash", "hash", "pending"); err != nil {
+					return err
+				}
+				head.Seq = entry.Seq
+				head.Hash = entry.Hash
+			}
+			batch = len(entries)
+			if batch == 0 {
+				return nil
+			}
+			return tx.UpdateRecord(&head)
+		})
+		if err != nil {
+			return sealed, err
+		}
+		sealed += batch
+		if batch < sealBatchSize {
+			return sealed, nil
+		}
+	}
+}
+
+// RunSealer seals the pending audit entries every interval, for as long as gomike runs
+func RunSealer(log *slog.Logger, dbSession *xDb.DBSession, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sealed, err := Seal(dbSession)
+		if err != nil {
+			log.Error("Failed to seal audit entries", slog.Int("sealed", sealed), slog.String("error", err.Error()))
+			continue
+		}
+		if sealed > 0 {
+			log.Debug("Audit entries sealed", slog.Int("sealed", sealed))
+		}
+	}
+}
+
+// EntryHash is the hex SHA-256 of everything an entry records, including the hash of the entry before it
+func EntryHash(entry xModels.AuditEntry) string {
+	content, _ := json.Marshal([]any{
+		entry.Seq,
+		entry.PrevHash,
+		entry.ID,
+		entry.Resource,
+		entry.RecordID,
+		entry.Operation,
+		entry.Actor,
+		entry.RequestID,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		entry.Changes,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
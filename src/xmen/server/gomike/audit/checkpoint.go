@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+const defaultCheckpointInterval = time.Hour
+
+// Signer signs checkpoints of the audit chain
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// SignerFromEnv reads GOMIKE_AUDIT_SIGNING_KEY, a base64 Ed25519 seed; it returns nil when checkpoints are not configured
+func SignerFromEnv() (*Signer, error) {
+	raw := os.Getenv("GOMIKE_AUDIT_SIGNING_KEY")
+	if raw == "" {
+		return nil, nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("GOMIKE_AUDIT_SIGNING_KEY must be a base64 %d byte Ed25519 seed", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}, nil
+}
+
+// PublicKeyFromEnv reads GOMIKE_AUDIT_PUBLIC_KEY, falling back to the public half of GOMIKE_AUDIT_SIGNING_KEY,
+// it returns nil when neither is set
+func PublicKeyFromEnv() (ed25519.PublicKey, error) {
+	if raw := os.Getenv("GOMIKE_AUDIT_PUBLIC_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("GOMIKE_AUDIT_PUBLIC_KEY must be a base64 %d byte Ed25519 public key", ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(key), nil
+	}
+	signer, err := SignerFromEnv()
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	return signer.key.Public().(ed25519.PublicKey), nil
+}
+
+// CheckpointIntervalFromEnv reads GOMIKE_AUDIT_CHECKPOINT_INTERVAL, e.g. 15m, one hour by default
+func CheckpointIntervalFromEnv() (time.Duration, error) {
+	raw := os.Getenv("GOMIKE_AUDIT_CHECKPOINT_INTERVAL")
+	if raw == "" {
+		return defaultCheckpointInterval, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid GOMIKE_AUDIT_CHECKPOINT_INTERVAL %q", raw)
+	}
+	return interval, nil
+}
+
+// KeyID names a public key in checkpoints so verifiers can tell which key signed them
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func checkpointMessage(checkpoint xModels.AuditCheckpoint) []byte {
+	return fmt.Appendf(nil, "gomike-audit-checkpoint\n%d\n%s\n%s", checkpoint.Seq, checkpoint.Hash, checkpoint.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// WriteCheckpoint signs the current head of the chain, unless nothing was appended since afterSeq
+func WriteCheckpoint(dbSession *xDb.DBSession, signer *Signer, afterSeq int64) (*xModels.AuditCheckpoint, error) {
+	head := xModels.AuditHead{}
+	if err := dbSession.ReadRecord(map[string]interface{}{"id": headID}, &head); err != nil {
+		return nil, fmt.Errorf("audit chain head: %w", err)
+	}
+	if head.Seq <= afterSeq {
+		return nil, nil
+	}
+	checkpoint := xModels.AuditCheckpoint{
+		ID:        uuid.New().String(),
+		Seq:       head.Seq,
+		Hash:      head.Hash,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		KeyID:     signer.keyID,
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(signer.key, checkpointMessage(checkpoint)))
+	if err := dbSession.CreateRecord(&checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// RunCheckpoints signs the head of the chain every interval, for as long as gomike runs
+func RunCheckpoints(log *slog.Logger, dbSession *xDb.DBSession, signer *Signer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastSeq int64
+	for range ticker.C {
+		checkpoint, err := WriteCheckpoint(dbSession, signer, lastSeq)
+		if err != nil {
+			log.Error("Failed to write audit checkpoint", slog.String("error", err.Error()))
+			continue
+		}
+		if checkpoint != nil {
+			lastSeq = checkpoint.Seq
+			log.Info("Audit checkpoint written", slog.Int64("seq", checkpoint.Seq), slog.String("key-id", checkpoint.KeyID))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// VerifyReport is the outcome of walking the audit chain, Broken is nil when every link holds
+type VerifyReport struct {
+	Entries     int64
+	Unchained   int64
+	Pending     int64
+	Checkpoints int
+	Signed      bool
+	Broken      *BrokenLink
+}
+
+// BrokenLink is the first place the chain stops proving itself
+type BrokenLink struct {
+	Seq     int64
+	EntryID string
+	Reason  string
+}
+
+func (b BrokenLink) String() string {
+	if b.EntryID == "" {
+		return fmt.Sprintf("seq %d: %s", b.Seq, b.Reason)
+	}
+	return fmt.Sprintf("seq %d (entry %s): %s", b.Seq, b.EntryID, b.Reason)
+}
+
+var errBroken = errors.New("audit chain broken")
+
+// Verify walks the chain from the first entry, recomputing every hash and checking it against the next
+// entry, the signed checkpoints and the head. Checkpoint signatures are only checked when publicKey is set.
+func Verify(dbSession *xDb.DBSession, publicKey ed25519.PublicKey) (VerifyReport, error) {
+	report := VerifyReport{Signed: publicKey != nil}
+
+	checkpoints := []xModels.AuditCheckpoint{}
+	if err := dbSession.FindRecords(map[string]interface{}{}, "seq, id", &checkpoints); err != nil {
+		return report, err
+	}
+	report.Checkpoints = len(checkpoints)
+	bySeq := map[int64][]xModels.AuditCheckpoint{}
+	for _, checkpoint := range checkpoints {
+		if publicKey != nil {
+			signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+			if err != nil || !ed25519.Verify(publicKey, checkpointMessage(checkpoint), signature) {
+				report.Broken = &BrokenLink{Seq: checkpoint.Seq, Reason: fmt.Sprintf("checkpoint %s has an invalid signature", checkpoint.ID)}
+				return report, nil
+			}
+		}
+		bySeq[checkpoint.Seq] = append(bySeq[checkpoint.Seq], checkpoint)
+	}
+
+	var lastSeq int64
+	lastHash := ""
+	entry := xModels.AuditEntry{}
+	err := dbSession.StreamRecords(map[string]interface{}{}, "seq, id", &entry, func() error {
+		// Entries not sealed yet have no place in the chain so far
+		if entry.Pending {
+			report.Pending++
+			return nil
+		}
+		// Entries written before the chain existed carry no sequence number
+		if entry.Seq == 0 {
+			report.Unchained++
+			return nil
+		}
+		broken := func(reason string) error {
+			report.Broken = &BrokenLink{Seq: entry.Seq, EntryID: entry.ID, Reason: reason}
+			return errBroken
+		}
+		switch {
+		case entry.Seq != lastSeq+1:
+			return broken(fmt.Sprintf("expected seq %d, entries are missing or were inserted", lastSeq+1))
+		case entry.PrevHash != lastHash:
+			return broken("previous hash does not match the entry before it")
+		case EntryHash(entry) != entry.Hash:
+			return broken("content does not match its hash")
+		}
+		for _, checkpoint := range bySeq[entry.Seq] {
+			if checkpoint.Hash != entry.Hash {
+				return broken(fmt.Sprintf("hash differs from signed checkpoint %s", checkpoint.ID))
+			}
+		}
+		report.Entries++
+		lastSeq = entry.Seq
+		lastHash = entry.Hash
+		return nil
+	})
+	if errors.Is(err, errBroken) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	if len(checkpoints) > 0 && checkpoints[len(checkpoints)-1].Seq > lastSeq {
+		last := checkpoints[len(checkpoints)-1]
+		report.Broken = &BrokenLink{Seq: lastSeq + 1, Reason: fmt.Sprintf("entries up to seq %d are vouched for by checkpoint %s but missing", last.Seq, last.ID)}
+		return report, nil
+	}
+	head := xModels.AuditHead{}
+	if err := dbSession.ReadRecord(map[string]interface{}{"id": headID}, &head); err == nil && (head.Seq != lastSeq || head.Hash != lastHash) {
+		report.Broken = &BrokenLink{Seq: lastSeq + 1, Reason: fmt.Sprintf("chain ends at seq %d but its head points at seq %d", lastSeq, head.Seq)}
+	}
+	return report, nil
+}
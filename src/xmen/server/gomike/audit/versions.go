@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+// KeepVersions stores a copy of every record shaped like model after each write, numbered per record under resource.
+// The write holds the row lock of the record, so versions of one record are numbered one at a time.
+func KeepVersions(resource string, model any) {
+	xDb.AddMutationHook(model, func(tx *xDb.DBSession, mutation xDb.Mutation) error {
+		recordID := fmt.Sprint(mutation.Key)
+		latest := &xModels.RecordVersion{}
+		next := int64(1)
+		err := tx.ReadFirstRecord(map[string]interface{}{"resource": resource, "record_id": recordID}, "version DESC", latest)
+		switch {
+		case err == nil:
+			next = latest.Version + 1
+		case strings.Contains(strings.ToLower(err.Error()), "record not found"):
+			latest = nil
+		default:
+			return err
+		}
+
+		data := ""
+		if mutation.After != nil {
+			encoded, err := json.Marshal(mutation.After)
+			if err != nil {
+				return err
+			}
+			data = string(encoded)
+		}
+		// Saving a record unchanged is not worth a version
+		if latest != nil && latest.Data == data {
+			return nil
+		}
+		return tx.CreateRecord(&xModels.RecordVersion{
+			ID:        uuid.New().String(),
+			Resource:  resource,
+			RecordID:  recordID,
+			Version:   next,
+			Operation: mutation.Operation,
+			Actor:     xRouter.Principal(mutation.Context),
+			RequestID: xRouter.RequestID(mutation.Context),
+			ValidFrom: time.Now().UTC(),
+			Data:      data,
+		})
+	})
+}
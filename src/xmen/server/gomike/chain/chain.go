@@ -0,0 +1,130 @@
+// Composable middleware chains for registering routes on a ServeMux
+
+package chain
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Middleware is a named handler decorator, the name is used for overrides, opt-outs and introspection
+type Middleware struct {
+	Name string
+	Wrap func(http.Handler) http.Handler
+}
+
+// RouteInfo describes a registered route and the middlewares wrapping it, outermost first
+type RouteInfo struct {
+	Pattern     string
+	Middlewares []string
+	Meta        any
+}
+
+type registry struct {
+	mu     sync.Mutex
+	routes []RouteInfo
+}
+
+// Chain registers routes on a mux wrapped in an ordered list of middlewares
+type Chain struct {
+	mux         *http.ServeMux
+	middlewares []Middleware
+	registry    *registry
+}
+
+type routeConfig struct {
+	middlewares []Middleware
+	meta        any
+}
+
+// RouteOption adjusts the middlewares or metadata of a single route
+type RouteOption func(*routeConfig)
+
+func New(mux *http.ServeMux) *Chain {
+	return &Chain{mux: mux, registry: &registry{}}
+}
+
+// Use appends a middleware, the first one added is the outermost
+func (c *Chain) Use(name string, wrap func(http.Handler) http.Handler) *Chain {
+	c.middlewares = append(c.middlewares, Middleware{Name: name, Wrap: wrap})
+	return c
+}
+
+// Group returns a chain sharing the mux and route registry whose middlewares can diverge from c
+func (c *Chain) Group() *Chain {
+	return &Chain{mux: c.mux, middlewares: slices.Clone(c.middlewares), registry: c.registry}
+}
+
+// Override replaces the named middleware in place, keeping its position in the chain
+func (c *Chain) Override(name string, wrap func(http.Handler) http.Handler) *Chain {
+	for i := range c.middlewares {
+		if c.middlewares[i].Name == name {
+			c.middlewares[i].Wrap = wrap
+			return c
+		}
+	}
+	panic(fmt.Sprintf("chain: cannot override unknown middleware %q", name))
+}
+
+// Remove drops the named middlewares from the chain
+func (c *Chain) Remove(names ...string) *Chain {
+	c.middlewares = withoutNames(c.middlewares, names)
+	return c
+}
+
+// Without opts a single route out of the named middlewares
+func Without(names ...string) RouteOption {
+	return func(rc *routeConfig) {
+		rc.middlewares = withoutNames(rc.middlewares, names)
+	}
+}
+
+// WithMeta attaches arbitrary metadata to a route, e.g. its API documentation
+func WithMeta(meta any) RouteOption {
+	return func(rc *routeConfig) {
+		rc.meta = meta
+	}
+}
+
+func withoutNames(middlewares []Middleware, names []string) []Middleware {
+	return slices.DeleteFunc(slices.Clone(middlewares), func(m Middleware) bool {
+		return slices.Contains(names, m.Name)
+	})
+}
+
+// Handle registers handler for pattern wrapped in the chain's middlewares
+func (c *Chain) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	rc := &routeConfig{middlewares: c.middlewares}
+	for _, opt := range opts {
+		opt(rc)
+	}
+	middlewares := rc.middlewares
+
+	names := make([]string, len(middlewares))
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i].Wrap(handler)
+		names[i] = middlewares[i].Name
+	}
+	c.mux.Handle(pattern, handler)
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+	c.registry.routes = append(c.registry.routes, RouteInfo{Pattern: pattern, Middlewares: names, Meta: rc.meta})
+}
+
+// Routes lists every route registered through this chain or its groups, in registration order
+func (c *Chain) Routes() []RouteInfo {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+	return slices.Clone(c.registry.routes)
+}
+
+// Dump writes one line per route with the middlewares wrapping it, outermost first
+func (c *Chain) Dump(w io.Writer) {
+	for _, route := range c.Routes() {
+		fmt.Fprintf(w, "%s\t%v\n", route.Pattern, route.Middlewares)
+	}
+}
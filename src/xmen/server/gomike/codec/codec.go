@@ -0,0 +1,151 @@
+// Codecs for the media types gomike can read and write, chosen by Accept and Content-Type
+
+package codec
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec encodes and decodes typed values for one media type
+type Codec interface {
+	MediaType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	registry     = map[string]Codec{}
+	aliases      = map[string]string{}
+	defaultCodec Codec
+)
+
+// Register makes a codec available for negotiation under its media type and any aliases
+func Register(c Codec, alias ...string) {
+	registry[c.MediaType()] = c
+	for _, a := range alias {
+		aliases[a] = c.MediaType()
+	}
+}
+
+func init() {
+	defaultCodec = JSON{}
+	Register(JSON{})
+	Register(XML{}, "text/xml")
+	Register(YAML{}, "application/x-yaml", "text/yaml")
+	Register(MsgPack{}, "application/x-msgpack", "application/vnd.msgpack")
+	Register(CSV{})
+	Register(NDJSON{}, "application/ndjson", "application/jsonl", "application/x-jsonlines")
+}
+
+func lookup(mediaType string) (Codec, bool) {
+	mediaType = strings.ToLower(mediaType)
+	if canonical, ok := aliases[mediaType]; ok {
+		mediaType = canonical
+	}
+	c, ok := registry[mediaType]
+	return c, ok
+}
+
+// ForContentType returns the codec for a request Content-Type, JSON when the header is empty
+func ForContentType(contentType string) (Codec, bool) {
+	if strings.TrimSpace(contentType) == "" {
+		return defaultCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	return lookup(mediaType)
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+	order     int
+}
+
+// Negotiate picks the codec best matching an Accept header, JSON when the header is empty or a wildcard
+func Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return defaultCodec, true
+	}
+	ranges := []mediaRange{}
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q, order: i})
+		}
+	}
+	// Highest quality first, then the most specific range, then the order the client listed them in
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	for _, r := range ranges {
+		switch {
+		case r.mediaType == "*/*":
+			return defaultCodec, true
+		case strings.HasSuffix(r.mediaType, "/*"):
+			prefix := strings.TrimSuffix(r.mediaType, "*")
+			if strings.HasPrefix(defaultCodec.MediaType(), prefix) {
+				return defaultCodec, true
+			}
+			for _, mediaType := range sortedMediaTypes() {
+				if strings.HasPrefix(mediaType, prefix) {
+					return registry[mediaType], true
+				}
+			}
+		default:
+			if c, ok := lookup(r.mediaType); ok {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func sortedMediaTypes() []string {
+	out := make([]string, 0, len(registry))
+	for mediaType := range registry {
+		out = append(out, mediaType)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Supported lists the registered media types, for 406 and 415 responses
+func Supported() []string {
+	return sortedMediaTypes()
+}
+
+// ContentType returns the Content-Type header value for responses written with c
+func ContentType(c Codec) string {
+	if _, binary := c.(MsgPack); binary {
+		return c.MediaType()
+	}
+	return c.MediaType() + "; charset=utf-8"
+}
@@ -0,0 +1,201 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSV writes a header row of field names followed by one row per record; it suits lists of flat records
+type CSV struct{}
+
+var errCSVShape = errors.New("csv supports only records and lists of records")
+
+func (CSV) MediaType() string { return "text/csv" }
+
+func (CSV) Marshal(v any) ([]byte, error) {
+	var rows []map[string]any
+	value := reflect.Indirect(reflect.ValueOf(v))
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if err := roundTrip(v, &rows); err != nil {
+			return nil, errCSVShape
+		}
+	case reflect.Struct, reflect.Map:
+		var row map[string]any
+		if err := roundTrip(v, &row); err != nil {
+			return nil, errCSVShape
+		}
+		rows = []map[string]any{row}
+	default:
+		return nil, errCSVShape
+	}
+
+	header := CSVHeader(value)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, name := range header {
+			record[i] = csvCell(row[name])
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Unmarshal reads a header row and decodes each following row into v, a pointer to a record or a slice of records
+func (CSV) Unmarshal(data []byte, v any) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < 2 {
+		return errors.New("csv body needs a header row and at least one record")
+	}
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer {
+		return errCSVShape
+	}
+	elemType := target.Elem().Type()
+	isList := elemType.Kind() == reflect.Slice
+	if isList {
+		elemType = elemType.Elem()
+	} else if len(records) != 2 {
+		return errors.New("csv body must hold exactly one record")
+	}
+
+	rows := []any{}
+	for _, record := range records[1:] {
+		row, err := CSVRow(records[0], record, elemType)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	if isList {
+		return roundTrip(rows, v)
+	}
+	return roundTrip(rows[0], v)
+}
+
+// CSVHeader returns the column names for records of the type held by value, in struct field order
+func CSVHeader(value reflect.Value) []string {
+	t := value.Type()
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		if value.Kind() == reflect.Map {
+			names := []string{}
+			for _, key := range value.MapKeys() {
+				names = append(names, fmt.Sprint(key.Interface()))
+			}
+			sort.Strings(names)
+			return names
+		}
+		return nil
+	}
+	names := []string{}
+	for _, field := range csvFields(t) {
+		names = append(names, field.name)
+	}
+	return names
+}
+
+type csvField struct {
+	name string
+	kind reflect.Kind
+}
+
+// csvFields lists the JSON names and kinds of the fields of t, flattening embedded structs like encoding/json does
+func csvFields(t reflect.Type) []csvField {
+	fields := []csvField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			fields = append(fields, csvFields(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, csvField{name: name, kind: field.Type.Kind()})
+	}
+	return fields
+}
+
+// CSVRow converts one CSV record into a JSON-shaped map typed after the fields of elemType
+func CSVRow(header, record []string, elemType reflect.Type) (map[string]any, error) {
+	kinds := map[string]reflect.Kind{}
+	if elemType.Kind() == reflect.Struct {
+		for _, field := range csvFields(elemType) {
+			kinds[field.name] = field.kind
+		}
+	}
+	row := map[string]any{}
+	for i, name := range header {
+		if i >= len(record) {
+			break
+		}
+		cell := record[i]
+		switch kinds[name] {
+		case reflect.Bool:
+			if cell == "" {
+				continue
+			}
+			b, err := strconv.ParseBool(cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", name, err)
+			}
+			row[name] = b
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if cell == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(cell, 64); err != nil {
+				return nil, fmt.Errorf("column %s: %q is not a number", name, cell)
+			}
+			row[name] = json.Number(cell)
+		case reflect.Struct:
+			// Times and other structs have no empty text form, an empty cell leaves them unset
+			if cell == "" {
+				continue
+			}
+			row[name] = cell
+		default:
+			row[name] = cell
+		}
+	}
+	return row, nil
+}
+
+func csvCell(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
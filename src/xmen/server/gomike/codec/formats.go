@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type JSON struct{}
+
+func (JSON) MediaType() string { return "application/json" }
+
+func (JSON) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type XML struct{}
+
+func (XML) MediaType() string { return "application/xml" }
+
+// Marshal wraps lists in an <items> root so the document stays well formed
+func (XML) Marshal(v any) ([]byte, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.IsValid() {
+		if err := xmlEncodable(value.Type(), map[reflect.Type]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		body, err := xml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), body...), nil
+	}
+	buf := bytes.NewBufferString(xml.Header + "<items>")
+	for i := 0; i < value.Len(); i++ {
+		body, err := xml.Marshal(value.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(body)
+	}
+	buf.WriteString("</items>")
+	return buf.Bytes(), nil
+}
+
+func (XML) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+var xmlMarshaler = reflect.TypeFor[xml.Marshaler]()
+
+// xmlEncodable refuses types holding a map encoding/xml cannot write, before any of the document is built
+func xmlEncodable(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] || t.Implements(xmlMarshaler) || reflect.PointerTo(t).Implements(xmlMarshaler) {
+		return nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Map:
+		return fmt.Errorf("xml cannot encode the map %s", t)
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return xmlEncodable(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && f.Tag.Get("xml") != "-" {
+				if err := xmlEncodable(f.Type, seen); err != nil {
+					return fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// NDJSON writes one JSON document per line, lists become one line per record
+type NDJSON struct{}
+
+func (NDJSON) MediaType() string { return "application/x-ndjson" }
+
+func (NDJSON) Marshal(v any) ([]byte, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(body, '\n'), nil
+	}
+	var buf bytes.Buffer
+	for i := 0; i < value.Len(); i++ {
+		body, err := json.Marshal(value.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(body)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes every line into v when it points to a slice, and a single line otherwise
+func (NDJSON) Unmarshal(data []byte, v any) error {
+	lines := [][]byte{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	target := reflect.ValueOf(v)
+	if target.Kind() == reflect.Pointer && target.Elem().Kind() == reflect.Slice {
+		return json.Unmarshal(append(append([]byte("["), bytes.Join(lines, []byte(","))...), ']'), v)
+	}
+	if len(lines) != 1 {
+		return errors.New("ndjson body must hold exactly one record")
+	}
+	return json.Unmarshal(lines[0], v)
+}
+
+// YAML goes through the JSON representation so field names match across every format
+type YAML struct{}
+
+func (YAML) MediaType() string { return "application/yaml" }
+
+func (YAML) Marshal(v any) ([]byte, error) {
+	var generic any
+	if err := roundTrip(v, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+func (YAML) Unmarshal(data []byte, v any) error {
+	var generic any
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	return roundTrip(generic, v)
+}
+
+type MsgPack struct{}
+
+func (MsgPack) MediaType() string { return "application/msgpack" }
+
+func (MsgPack) Marshal(v any) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (MsgPack) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+func roundTrip(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
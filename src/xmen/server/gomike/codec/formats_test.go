@@ -0,0 +1,44 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	xCodec "gomike/codec"
+	xModels "gomike/models"
+	xRouter "gomike/router"
+)
+
+func TestXMLWritesFieldChanges(t *testing.T) {
+	history := []xRouter.HistoryEntry{{
+		Operation: "update",
+		Actor:     "alice",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Changes: xModels.FieldChanges{
+			"Name": {Before: "Bob", After: "Robert"},
+			"Age":  {Before: float64(41), After: float64(42)},
+			"Nick": {After: "Bobby"},
+		},
+	}}
+	body, err := xCodec.XML{}.Marshal(history)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `<Changes><Change Field="Age"><Before>41</Before><After>42</After></Change>` +
+		`<Change Field="Name"><Before>Bob</Before><After>Robert</After></Change>` +
+		`<Change Field="Nick"><After>Bobby</After></Change></Changes>`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("XML = %s, want it to contain %s", body, want)
+	}
+}
+
+func TestXMLRefusesMaps(t *testing.T) {
+	type withMap struct {
+		Name   string
+		Labels map[string]string
+	}
+	if _, err := (xCodec.XML{}).Marshal([]withMap{{Name: "x"}}); err == nil || !strings.Contains(err.Error(), "Labels") {
+		t.Errorf("Marshal of a map-bearing type: err = %v, want one naming the field", err)
+	}
+}
@@ -0,0 +1,110 @@
+package codec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// ListWriter writes a list one record at a time, so a list of any length is never held in memory
+type ListWriter interface {
+	Write(record any) error
+	// Close terminates the list, it must be called even when the list is empty
+	Close() error
+}
+
+// NewListWriter returns a ListWriter writing records of elemType to w in the media type of c,
+// false when c cannot write a list incrementally
+func NewListWriter(c Codec, w io.Writer, elemType reflect.Type) (ListWriter, bool) {
+	switch c.(type) {
+	case JSON:
+		return &jsonArrayWriter{w: w}, true
+	case NDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, true
+	case CSV:
+		return &csvListWriter{w: csv.NewWriter(w), header: CSVHeader(reflect.New(elemType).Elem())}, true
+	}
+	return nil, false
+}
+
+type jsonArrayWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (j *jsonArrayWriter) Write(record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := []byte(",")
+	if !j.written {
+		separator = []byte("[")
+		j.written = true
+	}
+	if _, err := j.w.Write(separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonArrayWriter) Close() error {
+	closing := "]"
+	if !j.written {
+		closing = "[]"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(record any) error { return n.encoder.Encode(record) }
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+type csvListWriter struct {
+	w             *csv.Writer
+	header        []string
+	headerWritten bool
+}
+
+func (c *csvListWriter) Write(record any) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	var row map[string]any
+	if err := roundTrip(record, &row); err != nil {
+		return errCSVShape
+	}
+	fields := make([]string, len(c.header))
+	for i, name := range c.header {
+		fields[i] = csvCell(row[name])
+	}
+	if err := c.w.Write(fields); err != nil {
+		return err
+	}
+	// Flush per record so rows reach the client as they are read instead of piling up in the buffer
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvListWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvListWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(c.header)
+}
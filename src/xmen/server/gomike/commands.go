@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	xAudit "gomike/audit"
+	xModels "gomike/models"
+	xOpenAPI "gomike/openapi"
+	xSession "gomike/session"
+)
+
+// runCommand executes a gomike subcommand and returns the process exit code
+func runCommand(name string, args []string) int {
+	switch name {
+	case "openapi":
+		return runOpenAPICommand(args)
+	case "audit":
+		return runAuditCommand(args)
+	case "check":
+		return runCheckCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", name)
+		return 2
+	}
+}
+
+// runOpenAPICommand prints the OpenAPI document, or with -check compares it against a golden file
+func runOpenAPICommand(args []string) int {
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	check := flags.String("check", "", "golden file to compare the generated document against")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	api := registerRoutes(http.NewServeMux(), nil, logger, accessLogConfig{}, idempotencyConfig{})
+	spec, err := xOpenAPI.Build(apiTitle, apiVersion, api.Routes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building OpenAPI document: %v\n", err)
+		return 1
+	}
+	spec = append(spec, '\n')
+
+	if *check == "" {
+		os.Stdout.Write(spec)
+		return 0
+	}
+	golden, err := os.ReadFile(*check)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading golden file: %v\n", err)
+		return 1
+	}
+	if !bytes.Equal(golden, spec) {
+		fmt.Fprintf(os.Stderr, "OpenAPI document drifted from %s, regenerate it with `gomike openapi > %s`\n", *check, *check)
+		return 1
+	}
+	fmt.Println("OpenAPI document matches", *check)
+	return 0
+}
+
+// runAuditCommand runs `gomike audit verify`, walking the audit chain and reporting the first broken link
+func runAuditCommand(args []string) int {
+	if len(args) != 1 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "Usage: gomike audit verify")
+		return 2
+	}
+
+	publicKey, err := xAudit.PublicKeyFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading audit key: %v\n", err)
+		return 1
+	}
+	dbSession := xSession.GetDBSession(connStr)
+	report, err := xAudit.Verify(dbSession, publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying audit chain: %v\n", err)
+		return 1
+	}
+	if !report.Signed {
+		fmt.Fprintln(os.Stderr, "Warning: no GOMIKE_AUDIT_PUBLIC_KEY set, checkpoint signatures were not checked")
+	}
+	if report.Broken != nil {
+		fmt.Printf("Audit chain broken at %s\n", report.Broken)
+		return 1
+	}
+	fmt.Printf("Audit chain intact: %d entries, %d checkpoints", report.Entries, report.Checkpoints)
+	if report.Unchained > 0 {
+		fmt.Printf(", %d older entries predate the chain", report.Unchained)
+	}
+	if report.Pending > 0 {
+		fmt.Printf(", %d newer entries are waiting to be sealed", report.Pending)
+	}
+	fmt.Println()
+	return 0
+}
+
+// runCheckCommand runs `gomike check refs [-repair]`, reporting the ClonedFromRef values pointing at nothing
+// usable and with -repair orphaning those records
+func runCheckCommand(args []string) int {
+	if len(args) == 0 || args[0] != "refs" {
+		fmt.Fprintln(os.Stderr, "Usage: gomike check refs [-repair]")
+		return 2
+	}
+	flags := flag.NewFlagSet("check refs", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "clear the broken refs, the records keep Cloned set")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	dbSession := xSession.GetDBSession(connStr)
+	persons, err := xSession.BrokenCloneRefs[xModels.Person, xModels.Animal](dbSession, "person", "animal")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking person refs: %v\n", err)
+		return 1
+	}
+	animals, err := xSession.BrokenCloneRefs[xModels.Animal, xModels.Person](dbSession, "animal", "person")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking animal refs: %v\n", err)
+		return 1
+	}
+	broken := append(persons, animals...)
+	for _, ref := range broken {
+		fmt.Printf("%s %s: cloned_from_ref %s %s\n", ref.Resource, ref.ID, ref.Ref, ref.Problem)
+	}
+	if len(broken) == 0 {
+		fmt.Println("Clone refs intact")
+		return 0
+	}
+	if !*repair {
+		fmt.Printf("%d broken clone refs, run `gomike check refs -repair` to orphan those records\n", len(broken))
+		return 1
+	}
+
+	// Repairs are recorded in the audit trail and the versions like any other change, and so reach the outbox
+	if err := registerMutationHooks(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring mutation hooks: %v\n", err)
+		return 1
+	}
+	for _, ref := range broken {
+		if ref.Resource == "person" {
+			err = xSession.RepairCloneRef[xModels.Person](dbSession, ref.ID)
+		} else {
+			err = xSession.RepairCloneRef[xModels.Animal](dbSession, ref.ID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error repairing %s %s: %v\n", ref.Resource, ref.ID, err)
+			return 1
+		}
+	}
+	fmt.Printf("%d broken clone refs repaired\n", len(broken))
+	return 0
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	eventsPollInterval = time.Second
+	eventsHeartbeat    = 15 * time.Second
+	eventsBatchSize    = 500
+)
+
+// handleEvents streams the change feed of sealed audit entries as Server-Sent Events. Each event carries its
+// Seq as the SSE id, so a client reconnecting with Last-Event-ID picks up right after the last event it saw.
+// Heartbeats are sent while nothing changes so proxies keep the connection open.
+func handleEvents(log *slog.Logger, dbSession *xDb.DBSession) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+			conditions := map[string]interface{}{}
+			if resource := query.Get("resource"); resource != "" {
+				if resource != "person" && resource != "animal" {
+					writeProblem(w, req, http.StatusBadRequest, fmt.Sprintf("Unknown resource %q, expected person or animal", resource))
+					return
+				}
+				conditions["resource"] = resource
+			}
+			if id := query.Get("id"); id != "" {
+				conditions["record_id"] = id
+			}
+			kind := query.Get("kind")
+			if kind != "" && !slices.Contains(xRouter.EventKinds, kind) {
+				writeProblem(w, req, http.StatusBadRequest, fmt.Sprintf("Unknown kind %q", kind))
+				return
+			}
+
+			ctx := req.Context()
+			var cursor int64
+			if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+				seq, err := strconv.ParseInt(lastEventID, 10, 64)
+				if err != nil || seq < 0 {
+					writeProblem(w, req, http.StatusBadRequest, "Last-Event-ID must be the id of an event sent by this feed")
+					return
+				}
+				cursor = seq
+			} else {
+				// A fresh subscriber only hears about changes made from now on
+				seq, err := xSession.LatestEventSeq(dbSession.WithContext(ctx))
+				if err != nil {
+					log.Error("Failed to read the change feed", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+					writeProblem(w, req, http.StatusInternalServerError, "Failed to read the change feed")
+					return
+				}
+				cursor = seq
+			}
+
+			controller := http.NewResponseController(w)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			// Keeps buffering proxies such as nginx from holding events back
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			// An id without data is not dispatched but moves the client's Last-Event-ID, so a subscriber that has
+			// been sent nothing yet or whose filters skipped events still resumes from where the feed got to
+			fmt.Fprintf(w, "id: %d\n\n", cursor)
+			if err := controller.Flush(); err != nil {
+				log.Error("Change feed needs a flushable response", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+				return
+			}
+
+			poll := time.NewTicker(eventsPollInterval)
+			defer poll.Stop()
+			heartbeat := time.NewTicker(eventsHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				for {
+					entries, err := xSession.EventsAfter(dbSession.WithContext(ctx), conditions, cursor, eventsBatchSize)
+					if err != nil {
+						// Ending the stream makes the client reconnect and resume from the last event it got
+						log.Error("Failed to read the change feed", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+						return
+					}
+					for _, entry := range entries {
+						cursor = entry.Seq
+						event, err := xRouter.NewChangeEvent(entry)
+						if err != nil {
+							log.Error("Corrupt audit entry", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("entry", entry.ID), slog.String("error", err.Error()))
+							continue
+						}
+						if kind != "" && event.Kind != kind {
+							continue
+						}
+						data, err := json.Marshal(event)
+						if err != nil {
+							log.Error("Failed to encode change event", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+							continue
+						}
+						if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Kind, data); err != nil {
+							return
+						}
+					}
+					if len(entries) < eventsBatchSize {
+						break
+					}
+				}
+				if err := controller.Flush(); err != nil {
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-poll.C:
+				case <-heartbeat.C:
+					if _, err := fmt.Fprintf(w, ": heartbeat\nid: %d\n\n", cursor); err != nil {
+						return
+					}
+				}
+			}
+		},
+	)
+}
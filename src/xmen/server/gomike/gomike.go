@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	xAudit "gomike/audit"
+	xCodec "gomike/codec"
+	xMetrics "gomike/metrics"
+	xModels "gomike/models"
+	xOutbox "gomike/outbox"
 	xRouter "gomike/router"
 	xSession "gomike/session"
+	xTracing "gomike/tracing"
+	xWebhook "gomike/webhook"
 	xDb "lib/dbchef"
 
 	"github.com/google/uuid"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
 	run()
 }
 
@@ -24,6 +39,12 @@ var dbSession *xDb.DBSession
 
 const (
 	connStr = "host=localhost user=postgres password=Postsql.123 dbname=postgres port=5432 sslmode=disable TimeZone=Asia/Shanghai"
+
+	// Budgets for the router to produce a response, clients may lower them with Prefer: wait=N
+	defaultRouteTimeout = 2 * time.Second
+	readRouteTimeout    = 1 * time.Second
+	bulkRouteTimeout    = 60 * time.Second
+	retryAfterSeconds   = "1"
 )
 
 func run() {
@@ -35,26 +56,105 @@ func run() {
 	}
 	fmt.Printf("DB Session: %v\n", dbSession)
 
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	exporter, err := xTracing.NewExporterFromEnv("gomike")
+	if err != nil {
+		fmt.Printf("Error configuring trace exporter: %v\n", err)
+		return
+	}
+	if exporter != nil {
+		xTracing.SetExporter(exporter, func(err error) {
+			logger.Error("Failed to export spans", slog.String("error", err.Error()))
+		})
+	}
+
+	xDb.AddQueryObserver(xMetrics.ObserveDBQuery)
+	xDb.AddQueryObserver(xTracing.ObserveDBQuery)
+	xDb.SetActorResolver(xRouter.Principal)
+
+	if err := registerMutationHooks(); err != nil {
+		fmt.Printf("Error configuring mutation hooks: %v\n", err)
+		return
+	}
+	xMetrics.RegisterDBPoolMetrics(dbSession)
+
+	accessLog, err := accessLogConfigFromEnv(os.Stdout)
+	if err != nil {
+		fmt.Printf("Error configuring access log: %v\n", err)
+		return
+	}
+
+	idempotency, err := idempotencyConfigFromEnv(dbSession)
+	if err != nil {
+		fmt.Printf("Error configuring idempotency keys: %v\n", err)
+		return
+	}
+
 	err = xSession.SeedTables(dbSession)
 	if err != nil {
 		fmt.Printf("Error seeding tables: %v\n", err)
 		return
 	}
+	go purgeExpiredIdempotencyKeys(logger, idempotency)
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	mux := http.NewServeMux()
+	trashRetention, err := trashRetentionFromEnv()
+	if err != nil {
+		fmt.Printf("Error configuring the trash: %v\n", err)
+		return
+	}
+	go purgeTrash(logger, dbSession, trashRetention)
 
-	mux.Handle("GET /person/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.GetPerson(dbSession, logger))))))
-	mux.Handle("PUT /person/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.UpdatePerson(dbSession, logger))))))
-	mux.Handle("POST /person/", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.CreatePerson(dbSession, logger))))))
-	mux.Handle("PATCH /person/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.PatchPerson(dbSession, logger))))))
-	mux.Handle("DELETE /person/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.DeletePerson(dbSession, logger))))))
+	if err = xAudit.EnsureHead(dbSession); err != nil {
+		fmt.Printf("Error initialising audit chain: %v\n", err)
+		return
+	}
+	go xAudit.RunSealer(logger, dbSession, xAudit.SealInterval)
+	signer, err := xAudit.SignerFromEnv()
+	if err != nil {
+		fmt.Printf("Error configuring audit checkpoints: %v\n", err)
+		return
+	}
+	checkpointInterval, err := xAudit.CheckpointIntervalFromEnv()
+	if err != nil {
+		fmt.Printf("Error configuring audit checkpoints: %v\n", err)
+		return
+	}
+	if signer != nil {
+		go xAudit.RunCheckpoints(logger, dbSession, signer, checkpointInterval)
+	} else {
+		logger.Warn("GOMIKE_AUDIT_SIGNING_KEY is not set, the audit chain will not be checkpointed")
+	}
 
-	mux.Handle("GET /animal/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.GetAnimal(dbSession, logger))))))
-	mux.Handle("PUT /animal/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.UpdateAnimal(dbSession, logger))))))
-	mux.Handle("POST /animal/", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.CreateAnimal(dbSession, logger))))))
-	mux.Handle("PATCH /animal/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.PatchAnimal(dbSession, logger))))))
-	mux.Handle("DELETE /animal/{reqObjID}", handleRequest(handleWithLogger(logger, handleWithAuth(authMiddleware(logger), handleWithRouter(logger, xRouter.DeleteAnimal(dbSession, logger))))))
+	if err = xWebhook.EnsureCursor(dbSession); err != nil {
+		fmt.Printf("Error initialising webhooks: %v\n", err)
+		return
+	}
+	go xWebhook.Run(logger, dbSession)
+
+	outboxSink, err := xOutbox.SinkFromEnv()
+	if err != nil {
+		fmt.Printf("Error configuring the outbox: %v\n", err)
+		return
+	}
+	if outboxSink != nil {
+		if err = xOutbox.EnsureCursor(dbSession); err != nil {
+			fmt.Printf("Error initialising the outbox: %v\n", err)
+			return
+		}
+		go xOutbox.Relay(logger, dbSession, outboxSink)
+	} else {
+		logger.Warn("GOMIKE_OUTBOX_SINK is not set, changes will not be published through the outbox")
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, dbSession, logger, accessLog, idempotency)
+
+	go func() {
+		if err := startGRPCServer(dbSession, logger); err != nil {
+			logger.Error("gRPC server stopped", slog.String("error", err.Error()))
+		}
+	}()
 
 	fmt.Println("Starting server at port 8080")
 	if err = http.ListenAndServe("localhost:8080", mux); err != nil {
@@ -63,22 +163,105 @@ func run() {
 
 }
 
+func handleHealth(dbSession *xDb.DBSession) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if err := dbSession.WithContext(req.Context()).Ping(); err != nil {
+				writeProblem(w, req, http.StatusServiceUnavailable, fmt.Sprintf("Database unavailable: %s", err.Error()))
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok"))
+		},
+	)
+}
+
 func handleRequest(nextHandler http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			requestID := uuid.New().String()
-			ctx := context.WithValue(req.Context(), xRouter.RequestIDKey("requestID"), requestID)
+			requestID := req.Header.Get(xRouter.RequestIDHeader)
+			if !xRouter.ValidRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(xRouter.RequestIDHeader, requestID)
+			ctx := xRouter.WithRequestID(req.Context(), requestID)
 			req = req.WithContext(ctx)
 			nextHandler.ServeHTTP(w, req)
 		},
 	)
 }
 
+func handleWithRecovery(log *slog.Logger, nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			recorder := newResponseRecorder(w)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				log.Error("Recovered from panic",
+					slog.String("request-id", xRouter.RequestID(req.Context())),
+					slog.Any("panic", recovered),
+					slog.String("stack", string(debug.Stack())),
+				)
+				xMetrics.PanicsTotal.Inc(req.Pattern, "middleware")
+				if !recorder.wroteHeader {
+					writeProblem(recorder, req, http.StatusInternalServerError, "Internal server error")
+				}
+			}()
+			nextHandler.ServeHTTP(recorder, req)
+		},
+	)
+}
+
+func handleWithTracing(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+			if parent, ok := xTracing.Extract(req); ok {
+				ctx = xTracing.ContextWithRemoteSpanContext(ctx, parent)
+			}
+			ctx, span := xTracing.Start(ctx, req.Pattern, xTracing.SpanKindServer)
+			defer span.Finish()
+			span.SetAttribute("http.request.method", req.Method)
+			span.SetAttribute("http.route", req.Pattern)
+			span.SetAttribute("url.path", req.URL.Path)
+			span.SetAttribute("gomike.request_id", xRouter.RequestID(req.Context()))
+
+			recorder := newResponseRecorder(w)
+			nextHandler.ServeHTTP(recorder, req.WithContext(ctx))
+			span.SetAttribute("http.response.status_code", recorder.status)
+			if recorder.status >= http.StatusInternalServerError {
+				span.RecordError(fmt.Errorf("%s", http.StatusText(recorder.status)))
+			}
+		},
+	)
+}
+
+func handleWithMetrics(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := newResponseRecorder(w)
+			nextHandler.ServeHTTP(recorder, req)
+			status := strconv.Itoa(recorder.status)
+			xMetrics.HTTPRequestsTotal.Inc(req.Pattern, req.Method, status)
+			xMetrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), req.Pattern, req.Method, status)
+		},
+	)
+}
+
 func handleWithLogger(log *slog.Logger, nextHandler http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
 			log.Info("Received request",
-				slog.String("request-id", req.Context().Value(xRouter.RequestIDKey("requestID")).(string)),
+				slog.String("request-id", xRouter.RequestID(req.Context())),
+				slog.String("trace-id", xTracing.SpanContextFromContext(req.Context()).TraceID.String()),
 				slog.String("method", req.Method),
 				slog.String("url", req.URL.String()),
 			)
@@ -87,62 +270,283 @@ func handleWithLogger(log *slog.Logger, nextHandler http.Handler) http.Handler {
 	)
 }
 
-func handleWithAuth(authFunc func(context.Context, string) bool, nextHandler http.Handler) http.Handler {
+func handleWithAuth(authFunc func(context.Context, string) (string, bool), admins map[string]bool, nextHandler http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			isAuthorized := authFunc(req.Context(), req.Header.Get("Authorization"))
+			ctx, span := xTracing.Start(req.Context(), "middleware.auth", xTracing.SpanKindInternal)
+			defer span.Finish()
+			req = req.WithContext(ctx)
+
+			principal, isAuthorized := authFunc(req.Context(), req.Header.Get("Authorization"))
+			span.SetAttribute("auth.authorized", isAuthorized)
 			if !isAuthorized {
-				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte("Unauthorized"))
+				writeProblem(w, req, http.StatusUnauthorized, "Missing or invalid credentials")
 				return
 			}
+			span.SetAttribute("auth.principal", principal)
+			if recorder, ok := w.(*responseRecorder); ok {
+				recorder.principal = principal
+			}
+			req = req.WithContext(xRouter.WithPrincipal(req.Context(), principal))
+			if admins[principal] {
+				span.SetAttribute("auth.admin", true)
+				req = req.WithContext(xRouter.WithAdmin(req.Context()))
+			}
 			nextHandler.ServeHTTP(w, req)
 		},
 	)
 }
 
-func handleWithRouter(log *slog.Logger, routerFunc func(context.Context, string, io.ReadCloser) xRouter.RespDetail) http.Handler {
+func handleWithRouter(log *slog.Logger, timeout time.Duration, routerFunc func(context.Context, string, io.ReadCloser) xRouter.RespDetail) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			timeoutCtx, cancelCtx := context.WithTimeout(req.Context(), 2*time.Second)
+			respCodec, ok := xCodec.Negotiate(req.Header.Get("Accept"))
+			if !ok {
+				writeProblem(w, req, http.StatusNotAcceptable, fmt.Sprintf("Supported media types: %s", strings.Join(xCodec.Supported(), ", ")))
+				return
+			}
+			if req.ContentLength != 0 {
+				reqCodec, ok := xCodec.ForContentType(req.Header.Get("Content-Type"))
+				if !ok {
+					writeProblem(w, req, http.StatusUnsupportedMediaType, fmt.Sprintf("Supported media types: %s", strings.Join(xCodec.Supported(), ", ")))
+					return
+				}
+				req = req.WithContext(xRouter.WithRequestCodec(req.Context(), reqCodec))
+			}
+			req = req.WithContext(xRouter.WithPathValues(xRouter.WithQuery(req.Context(), req.URL.Query()), req))
+
+			budget := timeout
+			if wait, ok := preferredWait(req.Header); ok && wait < budget {
+				budget = wait
+				w.Header().Set("Preference-Applied", fmt.Sprintf("wait=%d", int(wait.Seconds())))
+			}
+
+			spanCtx, span := xTracing.Start(req.Context(), "middleware.timeout", xTracing.SpanKindInternal)
+			defer span.Finish()
+			span.SetAttribute("timeout.budget_ms", budget.Milliseconds())
+
+			outcome := routerOutcomeFrom(req.Context())
+			timeoutCtx, cancelCtx := context.WithTimeout(spanCtx, budget)
 			defer cancelCtx()
 			req = req.WithContext(timeoutCtx)
 			respChan := make(chan xRouter.RespDetail, 1)
 
+			if outcome != nil {
+				outcome.dispatched = true
+			}
 			go func() {
 				defer close(respChan)
-				respChan <- routerFunc(req.Context(), req.PathValue("reqObjID"), req.Body)
+				// A panic here is off the server goroutine and would otherwise crash gomike
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						log.Error("Recovered from panic in router",
+							slog.String("request-id", xRouter.RequestID(req.Context())),
+							slog.Any("panic", recovered),
+							slog.String("stack", string(debug.Stack())),
+						)
+						xMetrics.PanicsTotal.Inc(req.Pattern, "router")
+						respChan <- xRouter.RespDetail{
+							Statuscode: http.StatusInternalServerError,
+							Message:    []byte("Internal server error"),
+						}
+					}
+				}()
+				routerCtx, routerSpan := xTracing.Start(req.Context(), "router", xTracing.SpanKindInternal)
+				defer routerSpan.Finish()
+				routerSpan.SetAttribute("http.route", req.Pattern)
+				resp := routerFunc(routerCtx, req.PathValue("reqObjID"), req.Body)
+				routerSpan.SetAttribute("http.response.status_code", resp.Statuscode)
+				respChan <- resp
 			}()
 
 			select {
 			case <-timeoutCtx.Done():
-				log.Error("Request timed out", slog.String("request-id", req.Context().Value(xRouter.RequestIDKey("requestID")).(string)))
-				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-				w.WriteHeader(http.StatusRequestTimeout)
-				w.Write([]byte("Request timed out"))
-			case resp := <-respChan:
-				log.Info("Response received", slog.String("request-id", req.Context().Value(xRouter.RequestIDKey("requestID")).(string)))
-				switch resp.Type {
-				case "text/plain":
-					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-				case "application/json":
-					w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				span.RecordError(timeoutCtx.Err())
+				if outcome != nil {
+					// The router function carries on, its response goes to whoever tracks the outcome
+					outcome.late = respChan
+					outcome.codec = respCodec
+				}
+				if !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+					log.Warn("Client went away before the response was ready", slog.String("request-id", xRouter.RequestID(req.Context())))
+					return
 				}
+				log.Error("Request timed out", slog.String("request-id", xRouter.RequestID(req.Context())), slog.Duration("budget", budget))
+				xMetrics.RouterTimeoutsTotal.Inc(req.Pattern)
+				// The server ran out of time, not the client, so signal a retryable server-side condition
+				w.Header().Set("Retry-After", retryAfterSeconds)
+				writeProblem(w, req, http.StatusServiceUnavailable, fmt.Sprintf("Request could not be completed within %s", budget))
+			case resp := <-respChan:
+				log.Info("Response received", slog.String("request-id", xRouter.RequestID(req.Context())), slog.Int("status", resp.Statuscode))
+				writeRouterResponse(log, w, req, respCodec, resp)
+			}
+		},
+	)
+}
+
+// writeRouterResponse sends what a router function answered, encoding its Body with respCodec
+func writeRouterResponse(log *slog.Logger, w http.ResponseWriter, req *http.Request, respCodec xCodec.Codec, resp xRouter.RespDetail) {
+	if resp.Statuscode >= http.StatusBadRequest && resp.Body == nil {
+		writeProblem(w, req, resp.Statuscode, string(resp.Message))
+		return
+	}
+	if resp.Body != nil {
+		body, err := respCodec.Marshal(resp.Body)
+		if err != nil {
+			// The router already ran and its change may be committed, so this is a server fault, not a 406
+			log.Error("Failed to encode response", slog.String("request-id", xRouter.RequestID(req.Context())), slog.String("media-type", respCodec.MediaType()), slog.String("error", err.Error()))
+			writeProblem(w, req, http.StatusInternalServerError, fmt.Sprintf("Response cannot be encoded as %s: %s", respCodec.MediaType(), err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", xCodec.ContentType(respCodec))
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(resp.Statuscode)
+		w.Write(body)
+		return
+	}
+	switch resp.Type {
+	case "text/plain":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(resp.Statuscode)
+	w.Write(resp.Message)
+}
+
+// handleWithStream serves routes that write their body while it is produced. Exports are expected to
+// outlast the router timeouts, so they only stop when the client goes away.
+func handleWithStream(log *slog.Logger, filename string, routerFunc func(context.Context, string, io.ReadCloser) xRouter.RespDetail) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			respCodec, ok := xCodec.Negotiate(req.Header.Get("Accept"))
+			if !ok {
+				writeProblem(w, req, http.StatusNotAcceptable, fmt.Sprintf("Supported media types: %s", strings.Join(xCodec.Supported(), ", ")))
+				return
+			}
+			ctx := xRouter.WithResponseCodec(xRouter.WithQuery(req.Context(), req.URL.Query()), respCodec)
+			ctx, span := xTracing.Start(ctx, "router", xTracing.SpanKindInternal)
+			defer span.Finish()
+			span.SetAttribute("http.route", req.Pattern)
+
+			resp := routerFunc(ctx, req.PathValue("reqObjID"), req.Body)
+			span.SetAttribute("http.response.status_code", resp.Statuscode)
+			if resp.Statuscode >= http.StatusBadRequest {
+				writeProblem(w, req, resp.Statuscode, string(resp.Message))
+				return
+			}
+			if resp.Stream == nil {
 				w.WriteHeader(resp.Statuscode)
 				w.Write(resp.Message)
+				return
+			}
+
+			w.Header().Set("Content-Type", xCodec.ContentType(respCodec))
+			w.Header().Add("Vary", "Accept")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, filename, time.Now().UTC().Format("20060102T150405Z"), fileExtension(respCodec)))
+			w.WriteHeader(resp.Statuscode)
+			if err := resp.Stream(w); err != nil {
+				span.RecordError(err)
+				log.Error("Stream failed after the response started", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+				// The status is already sent, cutting the connection keeps a truncated file from passing as complete
+				panic(http.ErrAbortHandler)
 			}
 		},
 	)
 }
 
-func authMiddleware(log *slog.Logger) func(context.Context, string) bool {
-	return func(reqCtx context.Context, authHeader string) bool {
+func fileExtension(c xCodec.Codec) string {
+	switch c.(type) {
+	case xCodec.NDJSON:
+		return "ndjson"
+	case xCodec.CSV:
+		return "csv"
+	case xCodec.XML:
+		return "xml"
+	case xCodec.YAML:
+		return "yaml"
+	case xCodec.MsgPack:
+		return "msgpack"
+	}
+	return "json"
+}
+
+// preferredWait parses the wait preference of an RFC 7240 Prefer header, e.g. "Prefer: respond-async, wait=5"
+func preferredWait(header http.Header) (time.Duration, bool) {
+	for _, value := range header.Values("Prefer") {
+		for _, preference := range strings.Split(value, ",") {
+			name, arg, found := strings.Cut(strings.TrimSpace(preference), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "wait") {
+				continue
+			}
+			seconds, err := strconv.Atoi(strings.Trim(strings.TrimSpace(arg), `"`))
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+func authMiddleware(log *slog.Logger) func(context.Context, string) (string, bool) {
+	return func(reqCtx context.Context, authHeader string) (string, bool) {
 		if authHeader != "Basic bWl0ZXNoOk1pdGVzaC4xMjM=" {
-			log.Error("Unauthorized", slog.String("request-id", reqCtx.Value(xRouter.RequestIDKey("requestID")).(string)))
-			return false
+			reason := "invalid"
+			if authHeader == "" {
+				reason = "missing"
+			}
+			xMetrics.AuthFailuresTotal.Inc(reason)
+			log.Error("Unauthorized", slog.String("request-id", xRouter.RequestID(reqCtx)))
+			return "", false
 		}
-		log.Info("Authorization successful", slog.String("request-id", reqCtx.Value(xRouter.RequestIDKey("requestID")).(string)))
-		return true
+		log.Info("Authorization successful", slog.String("request-id", xRouter.RequestID(reqCtx)))
+		return basicAuthUser(authHeader), true
+	}
+}
+
+// adminPrincipalsFromEnv reads GOMIKE_ADMINS, a comma-separated list of principals allowed to run admin operations
+func adminPrincipalsFromEnv() map[string]bool {
+	admins := map[string]bool{}
+	for _, principal := range strings.Split(os.Getenv("GOMIKE_ADMINS"), ",") {
+		if principal = strings.TrimSpace(principal); principal != "" {
+			admins[principal] = true
+		}
+	}
+	return admins
+}
+
+// basicAuthUser returns the user name carried by a Basic Authorization header
+func basicAuthUser(authHeader string) string {
+	encoded, ok := strings.CutPrefix(authHeader, "Basic ")
+	if !ok {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	user, _, _ := strings.Cut(string(decoded), ":")
+	return user
+}
+
+// registerMutationHooks installs the integrity rules, the audit trail and the record versions, so every write
+// made by this process goes through them. The audit trail also feeds SSE, webhooks and the outbox.
+func registerMutationHooks() error {
+	ownerDeleteRule, err := xSession.OwnerDeleteRuleFromEnv()
+	if err != nil {
+		return err
+	}
+	xSession.EnforceOwnerDeleteRule(ownerDeleteRule)
+	cloneDeleteRule, err := xSession.CloneDeleteRuleFromEnv()
+	if err != nil {
+		return err
 	}
+	xSession.EnforceCloneRefs[xModels.Person]("person", cloneDeleteRule)
+	xSession.EnforceCloneRefs[xModels.Animal]("animal", cloneDeleteRule)
+	xAudit.Track("person", xModels.Person{})
+	xAudit.Track("animal", xModels.Animal{})
+	xAudit.KeepVersions("person", xModels.Person{})
+	xAudit.KeepVersions("animal", xModels.Animal{})
+	return nil
 }
@@ -0,0 +1,78 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	xRouter "gomike/router"
+	xDb "lib/dbchef"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Request is the body of a GraphQL POST
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Execute returns a router function running GraphQL requests against the model schema
+func Execute(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) xRouter.RespDetail {
+	schema, resources, err := NewSchema(dbSession)
+	if err != nil {
+		// The schema is derived from static model definitions, failing here is a programming error
+		panic(fmt.Sprintf("invalid GraphQL schema: %s", err.Error()))
+	}
+
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) xRouter.RespDetail {
+		var gqlReq Request
+		if err := json.NewDecoder(reqBody).Decode(&gqlReq); err != nil {
+			log.Error("Failed to decode GraphQL request", slog.String("request-id", xRouter.RequestID(reqCtx)), slog.String("error", err.Error()))
+			return xRouter.RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(fmt.Sprintf("Failed to decode GraphQL request: %s", err.Error())),
+			}
+		}
+		if gqlReq.Query == "" {
+			return xRouter.RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte("GraphQL query not provided"),
+			}
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  gqlReq.Query,
+			VariableValues: gqlReq.Variables,
+			OperationName:  gqlReq.OperationName,
+			Context:        withLoaders(reqCtx, dbSession, resources),
+		})
+		if result.HasErrors() {
+			log.Warn("GraphQL request returned errors", slog.String("request-id", xRouter.RequestID(reqCtx)), slog.Any("errors", result.Errors))
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			return xRouter.RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(fmt.Sprintf("Failed to marshal GraphQL response: %s", err.Error())),
+			}
+		}
+		return xRouter.RespDetail{
+			Statuscode: http.StatusOK,
+			Message:    body,
+			Type:       "application/json",
+		}
+	}
+}
+
+// Response documents the body returned for every GraphQL request
+type Response struct {
+	Data   map[string]interface{}   `json:"data"`
+	Errors []map[string]interface{} `json:"errors,omitempty"`
+}
@@ -0,0 +1,127 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	xDb "lib/dbchef"
+)
+
+// loader batches the clone relation lookups of one request into a single query per level,
+// relying on graphql-go resolving thunks breadth-first
+type loader struct {
+	res       *resource
+	dbSession *xDb.DBSession
+
+	mu             sync.Mutex
+	pendingIDs     map[string]bool
+	records        map[string]any
+	pendingOrigins map[string]bool
+	clones         map[string][]any
+}
+
+type loadersKey struct{}
+
+// withLoaders returns a copy of ctx carrying fresh loaders for every resource
+func withLoaders(ctx context.Context, dbSession *xDb.DBSession, resources []*resource) context.Context {
+	loaders := map[string]*loader{}
+	for _, res := range resources {
+		loaders[res.name] = &loader{
+			res:            res,
+			dbSession:      dbSession.WithContext(ctx),
+			pendingIDs:     map[string]bool{},
+			records:        map[string]any{},
+			pendingOrigins: map[string]bool{},
+			clones:         map[string][]any{},
+		}
+	}
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+func loaderFor(ctx context.Context, res *resource) *loader {
+	loaders, _ := ctx.Value(loadersKey{}).(map[string]*loader)
+	return loaders[res.name]
+}
+
+// byID queues id and returns a thunk resolving to the record, or nil when it does not exist
+func (l *loader) byID(id string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, done := l.records[id]; !done {
+		l.pendingIDs[id] = true
+	}
+	l.mu.Unlock()
+	return func() (interface{}, error) {
+		if err := l.flush(); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if record := l.records[id]; record != nil {
+			return record, nil
+		}
+		return nil, nil
+	}
+}
+
+// clonesOf queues originID and returns a thunk resolving to the records cloned from it
+func (l *loader) clonesOf(originID string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, done := l.clones[originID]; !done {
+		l.pendingOrigins[originID] = true
+	}
+	l.mu.Unlock()
+	return func() (interface{}, error) {
+		if err := l.flush(); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.clones[originID], nil
+	}
+}
+
+// flush runs one query for all queued IDs and one for all queued origins
+func (l *loader) flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pendingIDs) > 0 {
+		ids := keys(l.pendingIDs)
+		l.pendingIDs = map[string]bool{}
+		records, err := l.res.list(l.dbSession, map[string]interface{}{"id": ids}, "", -1)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			l.records[id] = nil
+		}
+		for _, record := range records {
+			l.records[l.res.id(record)] = record
+		}
+	}
+
+	if len(l.pendingOrigins) > 0 {
+		origins := keys(l.pendingOrigins)
+		l.pendingOrigins = map[string]bool{}
+		records, err := l.res.list(l.dbSession, map[string]interface{}{"cloned_from_ref": origins}, "", -1)
+		if err != nil {
+			return err
+		}
+		for _, origin := range origins {
+			l.clones[origin] = []any{}
+		}
+		for _, record := range records {
+			origin := l.res.clonedFromRef(record)
+			l.clones[origin] = append(l.clones[origin], record)
+		}
+	}
+	return nil
+}
+
+func keys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	return out
+}
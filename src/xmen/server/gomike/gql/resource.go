@@ -0,0 +1,274 @@
+// GraphQL API over persons and animals, with types derived from the models
+
+package gql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// resource binds a model type to its GraphQL types and the session CRUD operations
+type resource struct {
+	name      string
+	plural    string
+	typeName  string
+	kind      string
+	modelType reflect.Type
+	fields    []modelField
+
+	read   func(dbSession *xDb.DBSession, id string) (any, error)
+	list   func(dbSession *xDb.DBSession, conditions map[string]interface{}, after string, limit int) ([]any, error)
+	create func(dbSession *xDb.DBSession, record any) error
+	update func(dbSession *xDb.DBSession, record any, columns []string) error
+	delete func(dbSession *xDb.DBSession, record any) error
+	// trashed reports whether the ID belongs to a record in the trash
+	trashed func(dbSession *xDb.DBSession, id string) (bool, error)
+
+	object *graphql.Object
+	input  *graphql.InputObject
+	filter *graphql.InputObject
+	page   *graphql.Object
+}
+
+// modelField is a model struct field exposed as a GraphQL scalar
+type modelField struct {
+	name     string
+	column   string
+	index    []int
+	scalar   *graphql.Scalar
+	readonly bool
+}
+
+func newResource[T any](name, plural, kind string) *resource {
+	modelType := reflect.TypeOf((*T)(nil)).Elem()
+	res := &resource{
+		name:      name,
+		plural:    plural,
+		typeName:  modelType.Name(),
+		kind:      kind,
+		modelType: modelType,
+		fields:    modelFields(modelType, nil),
+	}
+	res.read = func(dbSession *xDb.DBSession, id string) (any, error) {
+		return xSession.ReadRecord[T](dbSession, id)
+	}
+	res.list = func(dbSession *xDb.DBSession, conditions map[string]interface{}, after string, limit int) ([]any, error) {
+		records, err := xSession.ListRecords[T](dbSession, conditions, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]any, len(records))
+		for i := range records {
+			out[i] = &records[i]
+		}
+		return out, nil
+	}
+	res.create = func(dbSession *xDb.DBSession, record any) error {
+		return xSession.CreateRecord(dbSession, *record.(*T))
+	}
+	res.update = func(dbSession *xDb.DBSession, record any, columns []string) error {
+		return xSession.UpdateColumns(dbSession, *record.(*T), columns...)
+	}
+	res.delete = func(dbSession *xDb.DBSession, record any) error {
+		return xSession.DeleteRecord(dbSession, *record.(*T))
+	}
+	res.trashed = func(dbSession *xDb.DBSession, id string) (bool, error) {
+		return xSession.InTrash[T](dbSession, id)
+	}
+	return res
+}
+
+// modelFields walks the exported scalar fields of a model, flattening embedded structs like encoding/json does
+func modelFields(t reflect.Type, parent []int) []modelField {
+	fields := []modelField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("gorm") == "-" {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, modelFields(field.Type, index)...)
+			continue
+		}
+		scalar := scalarFor(field.Type)
+		if scalar == nil {
+			continue
+		}
+		column := xSession.GormSettings(field.Tag.Get("gorm"))["column"]
+		if column == "" {
+			column = field.Name
+		}
+		fields = append(fields, modelField{
+			name:     lowerCamel(column),
+			column:   column,
+			index:    index,
+			scalar:   scalar,
+			readonly: field.Tag.Get("readonly") == "true",
+		})
+	}
+	return fields
+}
+
+func scalarFor(t reflect.Type) *graphql.Scalar {
+	if t == reflect.TypeOf(time.Time{}) {
+		return graphql.DateTime
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return graphql.String
+	case reflect.Bool:
+		return graphql.Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return graphql.Int
+	case reflect.Float32, reflect.Float64:
+		return graphql.Float
+	}
+	return nil
+}
+
+// lowerCamel turns a column name such as cloned_from_ref into clonedFromRef
+func lowerCamel(column string) string {
+	parts := strings.Split(column, "_")
+	for i, part := range parts {
+		if i > 0 && part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	name := strings.Join(parts, "")
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// sinceFilter names the filter bounding a time field from below, e.g. updatedSince for updatedAt
+func (f modelField) sinceFilter() (string, bool) {
+	if f.scalar != graphql.DateTime {
+		return "", false
+	}
+	prefix, ok := strings.CutSuffix(f.column, "_at")
+	if !ok {
+		return "", false
+	}
+	return lowerCamel(prefix + "_since"), true
+}
+
+func (res *resource) sinceColumn(filter string) (string, bool) {
+	for _, f := range res.fields {
+		if name, ok := f.sinceFilter(); ok && name == filter {
+			return f.column, true
+		}
+	}
+	return "", false
+}
+
+func (res *resource) field(name string) (modelField, bool) {
+	for _, f := range res.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return modelField{}, false
+}
+
+// value reads a field from a record pointer
+func (f modelField) value(record any) any {
+	return reflect.ValueOf(record).Elem().FieldByIndex(f.index).Interface()
+}
+
+// setFields copies GraphQL input values onto a record pointer
+func (res *resource) setFields(record any, input map[string]interface{}) error {
+	target := reflect.ValueOf(record).Elem()
+	for name, value := range input {
+		f, ok := res.field(name)
+		if !ok || f.name == "id" || f.readonly {
+			return fmt.Errorf("field %q cannot be set", name)
+		}
+		fieldValue := target.FieldByIndex(f.index)
+		if value == nil {
+			fieldValue.Set(reflect.Zero(fieldValue.Type()))
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if !v.Type().ConvertibleTo(fieldValue.Type()) {
+			return fmt.Errorf("field %q expects %s", name, fieldValue.Type())
+		}
+		fieldValue.Set(v.Convert(fieldValue.Type()))
+	}
+	return nil
+}
+
+// inputColumns lists the columns set by a GraphQL input, followed by the stamp columns
+func (res *resource) inputColumns(input map[string]interface{}) []string {
+	columns := []string{}
+	for _, f := range res.fields {
+		if _, ok := input[f.name]; ok {
+			columns = append(columns, f.column)
+		}
+	}
+	return append(columns, "updated_at", "updated_by")
+}
+
+// reset puts every field a client can set back to its zero value, the kind back to the resource's default
+func (res *resource) reset(record any) {
+	target := reflect.ValueOf(record).Elem()
+	for _, f := range res.fields {
+		if f.name == "id" || f.readonly {
+			continue
+		}
+		fieldValue := target.FieldByIndex(f.index)
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+	}
+	if f, ok := res.field("kind"); ok {
+		target.FieldByIndex(f.index).SetString(res.kind)
+	}
+}
+
+// newRecord allocates a record with a generated ID and the resource's default kind
+func (res *resource) newRecord(id string) any {
+	record := reflect.New(res.modelType)
+	if id == "" {
+		id = uuid.New().String()
+	}
+	if f, ok := res.field("id"); ok {
+		record.Elem().FieldByIndex(f.index).SetString(id)
+	}
+	if f, ok := res.field("kind"); ok {
+		record.Elem().FieldByIndex(f.index).SetString(res.kind)
+	}
+	return record.Interface()
+}
+
+func (res *resource) id(record any) string {
+	f, _ := res.field("id")
+	return f.value(record).(string)
+}
+
+func (res *resource) clonedFromRef(record any) string {
+	f, ok := res.field("clonedFromRef")
+	if !ok {
+		return ""
+	}
+	return f.value(record).(string)
+}
+
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "record not found")
+}
+
+func sessionFor(ctx context.Context, dbSession *xDb.DBSession) *xDb.DBSession {
+	return dbSession.WithContext(ctx)
+}
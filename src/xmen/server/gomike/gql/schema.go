@@ -0,0 +1,256 @@
+package gql
+
+import (
+	"fmt"
+	"time"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// resources lists the models exposed over GraphQL
+func resources() []*resource {
+	return []*resource{
+		newResource[xModels.Person]("person", "persons", "person"),
+		newResource[xModels.Animal]("animal", "animals", "animal"),
+	}
+}
+
+// NewSchema builds the GraphQL schema from the model definitions
+func NewSchema(dbSession *xDb.DBSession) (graphql.Schema, []*resource, error) {
+	all := resources()
+	for _, res := range all {
+		buildTypes(res)
+	}
+
+	queryFields := graphql.Fields{
+		"viewer": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The authenticated principal making the request",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return xRouter.Principal(p.Context), nil
+			},
+		},
+	}
+	mutationFields := graphql.Fields{}
+	for _, res := range all {
+		addQueries(queryFields, res, dbSession)
+		addMutations(mutationFields, res, dbSession)
+	}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queryFields}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutationFields}),
+	})
+	return schema, all, err
+}
+
+func buildTypes(res *resource) {
+	res.object = graphql.NewObject(graphql.ObjectConfig{
+		Name: res.typeName,
+		// A thunk lets the clone relations refer back to the type being defined
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := graphql.Fields{}
+			for _, f := range res.fields {
+				f := f
+				var fieldType graphql.Output = f.scalar
+				if f.name == "id" {
+					fieldType = graphql.NewNonNull(graphql.ID)
+				}
+				fields[f.name] = &graphql.Field{
+					Type: fieldType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return f.value(p.Source), nil
+					},
+				}
+			}
+			fields["clonedFrom"] = &graphql.Field{
+				Type:        res.object,
+				Description: fmt.Sprintf("The %s this one was cloned from", res.name),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ref := res.clonedFromRef(p.Source)
+					if ref == "" {
+						return nil, nil
+					}
+					return loaderFor(p.Context, res).byID(ref), nil
+				},
+			}
+			fields["clones"] = &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(res.object))),
+				Description: fmt.Sprintf("The %s records cloned from this one", res.plural),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFor(p.Context, res).clonesOf(res.id(p.Source)), nil
+				},
+			}
+			return fields
+		}),
+	})
+
+	inputFields := graphql.InputObjectConfigFieldMap{}
+	filterFields := graphql.InputObjectConfigFieldMap{}
+	for _, f := range res.fields {
+		if name, ok := f.sinceFilter(); ok {
+			filterFields[name] = &graphql.InputObjectFieldConfig{Type: f.scalar, Description: "Only records whose " + f.name + " is at or after this time"}
+		} else if f.scalar != graphql.DateTime {
+			filterFields[f.name] = &graphql.InputObjectFieldConfig{Type: f.scalar}
+		}
+		if f.name != "id" && !f.readonly {
+			inputFields[f.name] = &graphql.InputObjectFieldConfig{Type: f.scalar}
+		}
+	}
+	res.input = graphql.NewInputObject(graphql.InputObjectConfig{Name: res.typeName + "Input", Fields: inputFields})
+	res.filter = graphql.NewInputObject(graphql.InputObjectConfig{Name: res.typeName + "Filter", Fields: filterFields})
+	res.page = graphql.NewObject(graphql.ObjectConfig{
+		Name: res.typeName + "Page",
+		Fields: graphql.Fields{
+			"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(res.object)))},
+			"nextCursor": &graphql.Field{Type: graphql.String},
+		},
+	})
+}
+
+func addQueries(fields graphql.Fields, res *resource, dbSession *xDb.DBSession) {
+	fields[res.name] = &graphql.Field{
+		Type: res.object,
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			record, err := res.read(sessionFor(p.Context, dbSession), p.Args["id"].(string))
+			if isNotFound(err) {
+				return nil, nil
+			}
+			return record, err
+		},
+	}
+	fields[res.plural] = &graphql.Field{
+		Type: graphql.NewNonNull(res.page),
+		Args: graphql.FieldConfigArgument{
+			"filter": &graphql.ArgumentConfig{Type: res.filter},
+			"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+			"after":  &graphql.ArgumentConfig{Type: graphql.String, Description: "Cursor returned as nextCursor by the previous page"},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			conditions := map[string]interface{}{}
+			if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+				for name, value := range filter {
+					if column, ok := res.sinceColumn(name); ok {
+						since, _ := value.(time.Time)
+						conditions[column] = xDb.Since(since)
+						continue
+					}
+					f, _ := res.field(name)
+					conditions[f.column] = value
+				}
+			}
+			first, _ := p.Args["first"].(int)
+			if first <= 0 || first > maxPageSize {
+				return nil, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+			}
+			after, _ := p.Args["after"].(string)
+			records, err := res.list(sessionFor(p.Context, dbSession), conditions, after, first)
+			if err != nil {
+				return nil, err
+			}
+			page := map[string]interface{}{"items": records, "nextCursor": nil}
+			if len(records) == first {
+				page["nextCursor"] = res.id(records[len(records)-1])
+			}
+			return page, nil
+		},
+	}
+}
+
+func addMutations(fields graphql.Fields, res *resource, dbSession *xDb.DBSession) {
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+	inputArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(res.input)}
+
+	fields["create"+res.typeName] = &graphql.Field{
+		Type: graphql.NewNonNull(res.object),
+		Args: graphql.FieldConfigArgument{"input": inputArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			record := res.newRecord("")
+			if err := res.setFields(record, p.Args["input"].(map[string]interface{})); err != nil {
+				return nil, err
+			}
+			if err := res.create(sessionFor(p.Context, dbSession), record); err != nil {
+				return nil, err
+			}
+			return record, nil
+		},
+	}
+	fields["update"+res.typeName] = &graphql.Field{
+		Type:        graphql.NewNonNull(res.object),
+		Description: fmt.Sprintf("Replaces a %s, creating it when the ID is unknown", res.name),
+		Args:        graphql.FieldConfigArgument{"id": idArg, "input": inputArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			session := sessionFor(p.Context, dbSession)
+			id := p.Args["id"].(string)
+			record, err := res.read(session, id)
+			if isNotFound(err) {
+				trashed, err := res.trashed(session, id)
+				if err != nil {
+					return nil, err
+				}
+				if trashed {
+					return nil, fmt.Errorf("%s with ID %s is in the trash, restore it before replacing it", res.name, id)
+				}
+				record = res.newRecord(id)
+				if err := res.setFields(record, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				return record, res.create(session, record)
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Fields left out of the input are cleared, every column is written
+			res.reset(record)
+			if err := res.setFields(record, p.Args["input"].(map[string]interface{})); err != nil {
+				return nil, err
+			}
+			return record, res.update(session, record, xSession.WritableColumns(record))
+		},
+	}
+	fields["patch"+res.typeName] = &graphql.Field{
+		Type:        graphql.NewNonNull(res.object),
+		Description: fmt.Sprintf("Changes only the given fields of an existing %s", res.name),
+		Args:        graphql.FieldConfigArgument{"id": idArg, "input": inputArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			session := sessionFor(p.Context, dbSession)
+			record, err := res.read(session, p.Args["id"].(string))
+			if err != nil {
+				return nil, err
+			}
+			input := p.Args["input"].(map[string]interface{})
+			if err := res.setFields(record, input); err != nil {
+				return nil, err
+			}
+			return record, res.update(session, record, res.inputColumns(input))
+		},
+	}
+	fields["delete"+res.typeName] = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Boolean),
+		Args: graphql.FieldConfigArgument{"id": idArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			session := sessionFor(p.Context, dbSession)
+			record, err := res.read(session, p.Args["id"].(string))
+			if err != nil {
+				return false, err
+			}
+			if err := res.delete(session, record); err != nil {
+				return false, err
+			}
+			return true, nil
+		},
+	}
+}
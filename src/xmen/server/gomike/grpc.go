@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+
+	xGrpc "gomike/grpcserver"
+	xDb "lib/dbchef"
+)
+
+const grpcAddr = "localhost:9090"
+
+// startGRPCServer serves the gRPC API next to the HTTP one, with the same authentication
+func startGRPCServer(dbSession *xDb.DBSession, logger *slog.Logger) error {
+	listener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		return err
+	}
+	logger.Info("Starting gRPC server", slog.String("addr", grpcAddr))
+	return xGrpc.NewServer(dbSession, logger, authMiddleware(logger)).Serve(listener)
+}
@@ -0,0 +1,196 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+	"lib/xmenpb"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type animalServer struct {
+	xmenpb.UnimplementedAnimalServiceServer
+	dbSession *xDb.DBSession
+	log       *slog.Logger
+}
+
+func animalToProto(animal *xModels.Animal) *xmenpb.Animal {
+	return &xmenpb.Animal{
+		Id:            animal.ID,
+		Name:          animal.Name,
+		Kind:          animal.Kind,
+		Age:           int64(animal.Age),
+		Description:   animal.Description,
+		Breed:         animal.Breed,
+		Cloned:        animal.Cloned,
+		ClonedFromRef: animal.ClonedFromRef,
+	}
+}
+
+// applyAnimalFields copies msg onto animal, restricted to paths when it is not empty
+func applyAnimalFields(animal *xModels.Animal, msg *xmenpb.Animal, paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"name", "kind", "age", "description", "breed", "cloned", "cloned_from_ref"}
+	}
+	for _, path := range paths {
+		switch path {
+		case "name":
+			animal.Name = msg.GetName()
+		case "kind":
+			animal.Kind = msg.GetKind()
+		case "age":
+			animal.Age = int(msg.GetAge())
+		case "description":
+			animal.Description = msg.GetDescription()
+		case "breed":
+			animal.Breed = msg.GetBreed()
+		case "cloned":
+			animal.Cloned = msg.GetCloned()
+		case "cloned_from_ref":
+			animal.ClonedFromRef = msg.GetClonedFromRef()
+		default:
+			return fmt.Errorf("field %q cannot be changed", path)
+		}
+	}
+	return nil
+}
+
+func (s *animalServer) GetAnimal(ctx context.Context, req *xmenpb.GetAnimalRequest) (*xmenpb.Animal, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Animal ID not provided")
+	}
+	animalPtr, err := xSession.ReadRecord[xModels.Animal](s.dbSession.WithContext(ctx), req.GetId())
+	if err != nil {
+		s.log.Error("Error retrieving animal", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, toStatus(err, fmt.Sprintf("Animal with ID %s not found", req.GetId()))
+	}
+	return animalToProto(animalPtr), nil
+}
+
+func (s *animalServer) CreateAnimal(ctx context.Context, req *xmenpb.CreateAnimalRequest) (*xmenpb.Animal, error) {
+	if req.GetAnimal() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Animal not provided")
+	}
+	animal := xModels.Animal{
+		ID:   req.GetAnimal().GetId(),
+		Kind: "animal",
+	}
+	if animal.ID == "" {
+		animal.ID = uuid.New().String()
+	}
+	if err := applyAnimalFields(&animal, req.GetAnimal(), nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if animal.Kind == "" {
+		animal.Kind = "animal"
+	}
+	if err := xSession.CreateRecord(s.dbSession.WithContext(ctx), animal); err != nil {
+		s.log.Error("Failed to create animal", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	s.log.Info("New Animal created successfully", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("reqObjID", animal.ID))
+	return animalToProto(&animal), nil
+}
+
+func (s *animalServer) UpdateAnimal(ctx context.Context, req *xmenpb.UpdateAnimalRequest) (*xmenpb.Animal, error) {
+	if req.GetAnimal().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Animal ID not provided")
+	}
+	dbSession := s.dbSession.WithContext(ctx)
+	animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, req.GetAnimal().GetId())
+	if err != nil {
+		if status.Code(toStatus(err, "")) != codes.NotFound {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		trashed, err := xSession.InTrash[xModels.Animal](dbSession, req.GetAnimal().GetId())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if trashed {
+			return nil, status.Errorf(codes.FailedPrecondition, "Animal with ID %s is in the trash, restore it before replacing it", req.GetAnimal().GetId())
+		}
+		// Like PUT over HTTP, an unknown ID creates the animal
+		return s.CreateAnimal(ctx, &xmenpb.CreateAnimalRequest{Animal: req.GetAnimal()})
+	}
+	if err := applyAnimalFields(animalPtr, req.GetAnimal(), nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := xSession.UpdateRecord(dbSession, *animalPtr); err != nil {
+		s.log.Error("Failed to update animal", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return animalToProto(animalPtr), nil
+}
+
+func (s *animalServer) PatchAnimal(ctx context.Context, req *xmenpb.PatchAnimalRequest) (*xmenpb.Animal, error) {
+	if req.GetAnimal().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Animal ID not provided")
+	}
+	if req.GetUpdateMask() == nil || len(req.GetUpdateMask().GetPaths()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask must name the fields to change")
+	}
+	if !req.GetUpdateMask().IsValid(req.GetAnimal()) {
+		return nil, status.Error(codes.InvalidArgument, "update_mask names unknown fields")
+	}
+	req.GetUpdateMask().Normalize()
+
+	dbSession := s.dbSession.WithContext(ctx)
+	animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, req.GetAnimal().GetId())
+	if err != nil {
+		return nil, toStatus(err, fmt.Sprintf("Patch method is only allowed on existing records. Animal with ID %s not found", req.GetAnimal().GetId()))
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if err := applyAnimalFields(animalPtr, req.GetAnimal(), paths); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// Only the masked columns are written, so a mask can clear a field to its zero value
+	columns := append(slices.Clone(paths), "updated_at", "updated_by")
+	if err := xSession.UpdateColumns(dbSession, *animalPtr, columns...); err != nil {
+		s.log.Error("Failed to update animal", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return animalToProto(animalPtr), nil
+}
+
+func (s *animalServer) DeleteAnimal(ctx context.Context, req *xmenpb.DeleteAnimalRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Animal ID not provided")
+	}
+	dbSession := s.dbSession.WithContext(ctx)
+	animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, req.GetId())
+	if err != nil {
+		return nil, toStatus(err, fmt.Sprintf("Animal with ID %s not found", req.GetId()))
+	}
+	if err := xSession.DeleteRecord(dbSession, *animalPtr); err != nil {
+		s.log.Error("Failed to delete animal", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *animalServer) ListAnimals(ctx context.Context, req *xmenpb.ListAnimalsRequest) (*xmenpb.ListAnimalsResponse, error) {
+	limit := pageSize(req.GetPageSize())
+	animals, err := xSession.ListRecords[xModels.Animal](s.dbSession.WithContext(ctx), nil, req.GetPageToken(), limit)
+	if err != nil {
+		s.log.Error("Failed to list animals", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &xmenpb.ListAnimalsResponse{Animals: make([]*xmenpb.Animal, 0, len(animals))}
+	for i := range animals {
+		resp.Animals = append(resp.Animals, animalToProto(&animals[i]))
+	}
+	if len(animals) == limit {
+		resp.NextPageToken = animals[len(animals)-1].ID
+	}
+	return resp, nil
+}
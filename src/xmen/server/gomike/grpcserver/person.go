@@ -0,0 +1,196 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+	"lib/xmenpb"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type personServer struct {
+	xmenpb.UnimplementedPersonServiceServer
+	dbSession *xDb.DBSession
+	log       *slog.Logger
+}
+
+func personToProto(person *xModels.Person) *xmenpb.Person {
+	return &xmenpb.Person{
+		Id:            person.ID,
+		Name:          person.Name,
+		Kind:          person.Kind,
+		Age:           int64(person.Age),
+		Description:   person.Description,
+		Nationality:   person.Nationality,
+		Cloned:        person.Cloned,
+		ClonedFromRef: person.ClonedFromRef,
+	}
+}
+
+// applyPersonFields copies msg onto person, restricted to paths when it is not empty
+func applyPersonFields(person *xModels.Person, msg *xmenpb.Person, paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"name", "kind", "age", "description", "nationality", "cloned", "cloned_from_ref"}
+	}
+	for _, path := range paths {
+		switch path {
+		case "name":
+			person.Name = msg.GetName()
+		case "kind":
+			person.Kind = msg.GetKind()
+		case "age":
+			person.Age = int(msg.GetAge())
+		case "description":
+			person.Description = msg.GetDescription()
+		case "nationality":
+			person.Nationality = msg.GetNationality()
+		case "cloned":
+			person.Cloned = msg.GetCloned()
+		case "cloned_from_ref":
+			person.ClonedFromRef = msg.GetClonedFromRef()
+		default:
+			return fmt.Errorf("field %q cannot be changed", path)
+		}
+	}
+	return nil
+}
+
+func (s *personServer) GetPerson(ctx context.Context, req *xmenpb.GetPersonRequest) (*xmenpb.Person, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Person ID not provided")
+	}
+	personPtr, err := xSession.ReadRecord[xModels.Person](s.dbSession.WithContext(ctx), req.GetId())
+	if err != nil {
+		s.log.Error("Error retrieving person", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, toStatus(err, fmt.Sprintf("Person with ID %s not found", req.GetId()))
+	}
+	return personToProto(personPtr), nil
+}
+
+func (s *personServer) CreatePerson(ctx context.Context, req *xmenpb.CreatePersonRequest) (*xmenpb.Person, error) {
+	if req.GetPerson() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Person not provided")
+	}
+	person := xModels.Person{
+		ID:   req.GetPerson().GetId(),
+		Kind: "person",
+	}
+	if person.ID == "" {
+		person.ID = uuid.New().String()
+	}
+	if err := applyPersonFields(&person, req.GetPerson(), nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if person.Kind == "" {
+		person.Kind = "person"
+	}
+	if err := xSession.CreateRecord(s.dbSession.WithContext(ctx), person); err != nil {
+		s.log.Error("Failed to create person", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	s.log.Info("New Person created successfully", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("reqObjID", person.ID))
+	return personToProto(&person), nil
+}
+
+func (s *personServer) UpdatePerson(ctx context.Context, req *xmenpb.UpdatePersonRequest) (*xmenpb.Person, error) {
+	if req.GetPerson().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Person ID not provided")
+	}
+	dbSession := s.dbSession.WithContext(ctx)
+	personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, req.GetPerson().GetId())
+	if err != nil {
+		if status.Code(toStatus(err, "")) != codes.NotFound {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		trashed, err := xSession.InTrash[xModels.Person](dbSession, req.GetPerson().GetId())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if trashed {
+			return nil, status.Errorf(codes.FailedPrecondition, "Person with ID %s is in the trash, restore it before replacing it", req.GetPerson().GetId())
+		}
+		// Like PUT over HTTP, an unknown ID creates the person
+		return s.CreatePerson(ctx, &xmenpb.CreatePersonRequest{Person: req.GetPerson()})
+	}
+	if err := applyPersonFields(personPtr, req.GetPerson(), nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := xSession.UpdateRecord(dbSession, *personPtr); err != nil {
+		s.log.Error("Failed to update person", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return personToProto(personPtr), nil
+}
+
+func (s *personServer) PatchPerson(ctx context.Context, req *xmenpb.PatchPersonRequest) (*xmenpb.Person, error) {
+	if req.GetPerson().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Person ID not provided")
+	}
+	if req.GetUpdateMask() == nil || len(req.GetUpdateMask().GetPaths()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask must name the fields to change")
+	}
+	if !req.GetUpdateMask().IsValid(req.GetPerson()) {
+		return nil, status.Error(codes.InvalidArgument, "update_mask names unknown fields")
+	}
+	req.GetUpdateMask().Normalize()
+
+	dbSession := s.dbSession.WithContext(ctx)
+	personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, req.GetPerson().GetId())
+	if err != nil {
+		return nil, toStatus(err, fmt.Sprintf("Patch method is only allowed on existing records. Person with ID %s not found", req.GetPerson().GetId()))
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if err := applyPersonFields(personPtr, req.GetPerson(), paths); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// Only the masked columns are written, so a mask can clear a field to its zero value
+	columns := append(slices.Clone(paths), "updated_at", "updated_by")
+	if err := xSession.UpdateColumns(dbSession, *personPtr, columns...); err != nil {
+		s.log.Error("Failed to update person", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return personToProto(personPtr), nil
+}
+
+func (s *personServer) DeletePerson(ctx context.Context, req *xmenpb.DeletePersonRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Person ID not provided")
+	}
+	dbSession := s.dbSession.WithContext(ctx)
+	personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, req.GetId())
+	if err != nil {
+		return nil, toStatus(err, fmt.Sprintf("Person with ID %s not found", req.GetId()))
+	}
+	if err := xSession.DeleteRecord(dbSession, *personPtr); err != nil {
+		s.log.Error("Failed to delete person", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, writeStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *personServer) ListPersons(ctx context.Context, req *xmenpb.ListPersonsRequest) (*xmenpb.ListPersonsResponse, error) {
+	limit := pageSize(req.GetPageSize())
+	persons, err := xSession.ListRecords[xModels.Person](s.dbSession.WithContext(ctx), nil, req.GetPageToken(), limit)
+	if err != nil {
+		s.log.Error("Failed to list persons", slog.String("request-id", xRouter.RequestID(ctx)), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &xmenpb.ListPersonsResponse{Persons: make([]*xmenpb.Person, 0, len(persons))}
+	for i := range persons {
+		resp.Persons = append(resp.Persons, personToProto(&persons[i]))
+	}
+	if len(persons) == limit {
+		resp.NextPageToken = persons[len(persons)-1].ID
+	}
+	return resp, nil
+}
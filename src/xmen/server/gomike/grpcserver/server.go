@@ -0,0 +1,154 @@
+// gRPC API sharing the session and dbchef layers with the HTTP routes
+
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	xMetrics "gomike/metrics"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xTracing "gomike/tracing"
+	xDb "lib/dbchef"
+	"lib/xmenpb"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// AuthFunc validates an Authorization value and returns the principal, it is shared with the HTTP mux
+type AuthFunc func(context.Context, string) (string, bool)
+
+// NewServer builds a gRPC server exposing the person and animal services with health and reflection
+func NewServer(dbSession *xDb.DBSession, log *slog.Logger, authFunc AuthFunc) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestIDInterceptor(),
+		recoveryInterceptor(log),
+		tracingInterceptor(),
+		authInterceptor(authFunc),
+	))
+	xmenpb.RegisterPersonServiceServer(server, &personServer{dbSession: dbSession, log: log})
+	xmenpb.RegisterAnimalServiceServer(server, &animalServer{dbSession: dbSession, log: log})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(xmenpb.PersonService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(xmenpb.AnimalService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	return server
+}
+
+// isPublic reports whether a method may be called without credentials
+func isPublic(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func requestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requestID := firstMetadata(ctx, strings.ToLower(xRouter.RequestIDHeader))
+		if !xRouter.ValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(xRouter.RequestIDHeader), requestID))
+		return handler(xRouter.WithRequestID(ctx, requestID), req)
+	}
+}
+
+func recoveryInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Error("Recovered from panic in gRPC handler",
+					slog.String("request-id", xRouter.RequestID(ctx)),
+					slog.String("method", info.FullMethod),
+					slog.Any("panic", recovered),
+					slog.String("stack", string(debug.Stack())),
+				)
+				xMetrics.PanicsTotal.Inc(info.FullMethod, "grpc")
+				err = status.Error(codes.Internal, "Internal server error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func tracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if parent, ok := xTracing.ParseTraceparent(firstMetadata(ctx, xTracing.TraceparentHeader)); ok {
+			ctx = xTracing.ContextWithRemoteSpanContext(ctx, parent)
+		}
+		ctx, span := xTracing.Start(ctx, info.FullMethod, xTracing.SpanKindServer)
+		defer span.Finish()
+		span.SetAttribute("rpc.system", "grpc")
+		span.SetAttribute("rpc.method", info.FullMethod)
+		span.SetAttribute("gomike.request_id", xRouter.RequestID(ctx))
+		resp, err := handler(ctx, req)
+		span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+		span.RecordError(err)
+		return resp, err
+	}
+}
+
+func authInterceptor(authFunc AuthFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		principal, ok := authFunc(ctx, firstMetadata(ctx, "authorization"))
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "Missing or invalid credentials")
+		}
+		return handler(xRouter.WithPrincipal(ctx, principal), req)
+	}
+}
+
+// toStatus maps a session error onto a gRPC status
+func toStatus(err error, notFound string) error {
+	if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+		return status.Error(codes.NotFound, notFound)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// writeStatus maps the error of a create, update or delete onto a gRPC status, telling integrity refusals apart
+func writeStatus(err error) error {
+	switch {
+	case strings.Contains(err.Error(), xSession.ErrInvalidCloneRef.Error()):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case strings.Contains(err.Error(), xSession.ErrOriginHasClones.Error()), strings.Contains(err.Error(), xSession.ErrOwnerHasAnimals.Error()):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func pageSize(requested int32) int {
+	switch {
+	case requested <= 0:
+		return 50
+	case requested > 500:
+		return 500
+	}
+	return int(requested)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	xCodec "gomike/codec"
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	defaultIdempotencyTTL     = 24 * time.Hour
+)
+
+type idempotencyConfig struct {
+	ttl       time.Duration
+	dbSession *xDb.DBSession
+}
+
+// idempotencyConfigFromEnv reads GOMIKE_IDEMPOTENCY_TTL, how long a key and its response are kept, e.g. 24h
+func idempotencyConfigFromEnv(dbSession *xDb.DBSession) (idempotencyConfig, error) {
+	cfg := idempotencyConfig{ttl: defaultIdempotencyTTL, dbSession: dbSession}
+	if raw := os.Getenv("GOMIKE_IDEMPOTENCY_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return cfg, fmt.Errorf("invalid GOMIKE_IDEMPOTENCY_TTL %q", raw)
+		}
+		cfg.ttl = ttl
+	}
+	return cfg, nil
+}
+
+// handleWithIdempotency lets clients retry a POST safely: the first response sent under an Idempotency-Key
+// is stored and replayed for the TTL, while reusing the key for a different request is refused. The key is
+// only released when the request failed before reaching its router function, once dispatched its outcome is kept
+func handleWithIdempotency(log *slog.Logger, cfg idempotencyConfig, nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			key := req.Header.Get(idempotencyKeyHeader)
+			if req.Method != http.MethodPost || key == "" {
+				nextHandler.ServeHTTP(w, req)
+				return
+			}
+			if !xRouter.ValidRequestID(key) {
+				writeProblem(w, req, http.StatusBadRequest, "Idempotency-Key must be 1 to 128 letters, digits or -_.:/+=@")
+				return
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				writeProblem(w, req, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %s", err.Error()))
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			principal := xRouter.Principal(req.Context())
+			now := time.Now()
+			record := xModels.IdempotencyKey{
+				ID:          idempotencyRecordID(principal, key),
+				Key:         key,
+				Principal:   principal,
+				RequestHash: idempotencyRequestHash(req, body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(cfg.ttl),
+			}
+			// The record outlives the request, so storing the outcome must not be cut short by its cancellation
+			db := cfg.dbSession.WithContext(context.WithoutCancel(req.Context()))
+
+			if err := xSession.CreateRecord(db, record); err != nil {
+				stored, readErr := xSession.ReadRecord[xModels.IdempotencyKey](db, record.ID)
+				if readErr != nil {
+					log.Error("Idempotency store unavailable", slog.String("request-id", xRouter.RequestID(req.Context())), slog.String("error", err.Error()))
+					writeProblem(w, req, http.StatusServiceUnavailable, "Idempotency-Key could not be checked, retry later")
+					return
+				}
+				if !stored.ExpiresAt.After(now) {
+					// The key expired but was not purged yet, it is free to be claimed again
+					if xSession.DeleteRecord(db, *stored) != nil || xSession.CreateRecord(db, record) != nil {
+						w.Header().Set("Retry-After", retryAfterSeconds)
+						writeProblem(w, req, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+						return
+					}
+				} else {
+					replayIdempotent(log, w, req, stored, record.RequestHash)
+					return
+				}
+			}
+
+			outcome := &routerOutcome{}
+			req = req.WithContext(context.WithValue(req.Context(), routerOutcomeKey{}, outcome))
+			capture := &idempotencyCapture{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					xSession.DeleteRecord(db, record)
+					panic(recovered)
+				}
+			}()
+			nextHandler.ServeHTTP(capture, req)
+
+			if outcome.late != nil {
+				// The router timed out but is still running and may yet take effect, the key stays in progress
+				// so retries are answered 409 until its response is ready to be stored
+				go func() {
+					resp, ok := <-outcome.late
+					if !ok {
+						releaseIdempotencyKey(log, db, req, record)
+						return
+					}
+					late := &idempotencyCapture{ResponseWriter: &discardResponse{header: http.Header{}}, status: http.StatusOK}
+					writeRouterResponse(log, late, req, outcome.codec, resp)
+					storeIdempotent(log, db, req, record, late)
+				}()
+				return
+			}
+			// A server error before the router was dispatched means nothing happened, release the key so it can be retried
+			if capture.status >= http.StatusInternalServerError && !outcome.dispatched {
+				releaseIdempotencyKey(log, db, req, record)
+				return
+			}
+			storeIdempotent(log, db, req, record, capture)
+		},
+	)
+}
+
+func storeIdempotent(log *slog.Logger, db *xDb.DBSession, req *http.Request, record xModels.IdempotencyKey, capture *idempotencyCapture) {
+	record.Status = capture.status
+	record.ContentType = capture.Header().Get("Content-Type")
+	record.Body = capture.body.Bytes()
+	if err := xSession.UpdateRecord(db, record); err != nil {
+		log.Error("Failed to store idempotent response", slog.String("request-id", xRouter.RequestID(req.Context())), slog.String("error", err.Error()))
+	}
+}
+
+func releaseIdempotencyKey(log *slog.Logger, db *xDb.DBSession, req *http.Request, record xModels.IdempotencyKey) {
+	if err := xSession.DeleteRecord(db, record); err != nil {
+		log.Error("Failed to release idempotency key", slog.String("request-id", xRouter.RequestID(req.Context())), slog.String("error", err.Error()))
+	}
+}
+
+func replayIdempotent(log *slog.Logger, w http.ResponseWriter, req *http.Request, stored *xModels.IdempotencyKey, requestHash string) {
+	switch {
+	case stored.RequestHash != requestHash:
+		writeProblem(w, req, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	case stored.Status == 0:
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		writeProblem(w, req, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+	default:
+		log.Info("Replaying idempotent response", slog.String("request-id", xRouter.RequestID(req.Context())), slog.Int("status", stored.Status))
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+	}
+}
+
+// routerOutcome tells handleWithIdempotency what became of the router function behind a request
+type routerOutcome struct {
+	// dispatched is set once the router function started, from then on the request may have taken effect
+	dispatched bool
+	// late delivers the response of a router function still running when the request ended, on timeout or hang-up
+	late  <-chan xRouter.RespDetail
+	codec xCodec.Codec
+}
+
+type routerOutcomeKey struct{}
+
+// routerOutcomeFrom returns the outcome tracked for the request, nil when no one asked for it
+func routerOutcomeFrom(ctx context.Context) *routerOutcome {
+	outcome, _ := ctx.Value(routerOutcomeKey{}).(*routerOutcome)
+	return outcome
+}
+
+// idempotencyRecordID scopes keys to the principal, two clients picking the same key must not see each other's responses
+func idempotencyRecordID(principal, key string) string {
+	sum := sha256.Sum256([]byte(principal + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func idempotencyRequestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// purgeExpiredIdempotencyKeys deletes expired keys in the background, expired keys are also reclaimed on use
+func purgeExpiredIdempotencyKeys(log *slog.Logger, cfg idempotencyConfig) {
+	ticker := time.NewTicker(min(cfg.ttl, time.Hour))
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := xSession.DeleteExpiredIdempotencyKeys(cfg.dbSession.WithContext(context.Background()), time.Now())
+		if err != nil {
+			log.Error("Failed to purge idempotency keys", slog.String("error", err.Error()))
+			continue
+		}
+		if deleted > 0 {
+			log.Info("Purged expired idempotency keys", slog.Int64("deleted", deleted))
+		}
+	}
+}
+
+// idempotencyCapture keeps a copy of the response so it can be replayed
+type idempotencyCapture struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (c *idempotencyCapture) WriteHeader(statusCode int) {
+	if !c.wroteHeader {
+		c.status = statusCode
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *idempotencyCapture) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *idempotencyCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *idempotencyCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// discardResponse stands in for a client that is no longer waiting
+type discardResponse struct {
+	header http.Header
+}
+
+func (d *discardResponse) Header() http.Header         { return d.header }
+func (d *discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardResponse) WriteHeader(int)             {}
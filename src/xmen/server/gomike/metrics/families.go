@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"database/sql"
+
+	xDb "lib/dbchef"
+)
+
+var (
+	HTTPRequestsTotal = NewCounterVec("gomike_http_requests_total",
+		"Total number of HTTP requests by route pattern, method and status.",
+		"route", "method", "status")
+	HTTPRequestDuration = NewHistogramVec("gomike_http_request_duration_seconds",
+		"HTTP request latency by route pattern, method and status.",
+		DefaultBuckets, "route", "method", "status")
+	RouterTimeoutsTotal = NewCounterVec("gomike_router_timeouts_total",
+		"Total number of requests that timed out in the router.",
+		"route")
+	AuthFailuresTotal = NewCounterVec("gomike_auth_failures_total",
+		"Total number of requests rejected by the auth middleware by reason.",
+		"reason")
+	PanicsTotal = NewCounterVec("gomike_panics_total",
+		"Total number of panics recovered by route and where they were caught.",
+		"route", "location")
+	DBQueryDuration = NewHistogramVec("gomike_db_query_duration_seconds",
+		"Database operation latency by operation, model and outcome.",
+		DefaultBuckets, "operation", "model", "outcome")
+)
+
+// ObserveDBQuery records a dbchef operation, it is meant to be installed with xDb.AddQueryObserver
+func ObserveDBQuery(event xDb.QueryEvent) {
+	outcome := "success"
+	if event.Err != nil {
+		outcome = "error"
+	}
+	DBQueryDuration.Observe(event.Duration.Seconds(), event.Operation, event.Model, outcome)
+}
+
+// RegisterDBPoolMetrics exposes the connection pool statistics of dbSession
+func RegisterDBPoolMetrics(dbSession *xDb.DBSession) {
+	poolStat := func(pick func(sql.DBStats) float64) func() float64 {
+		return func() float64 {
+			stats, err := dbSession.PoolStats()
+			if err != nil {
+				return 0
+			}
+			return pick(stats)
+		}
+	}
+	NewGaugeFunc("gomike_db_pool_open_connections", "Number of established database connections.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	NewGaugeFunc("gomike_db_pool_in_use_connections", "Number of database connections currently in use.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	NewGaugeFunc("gomike_db_pool_idle_connections", "Number of idle database connections.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	// WaitCount and WaitDuration only grow over the life of the pool
+	NewCounterFunc("gomike_db_pool_wait_total", "Total number of connections waited for.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	NewCounterFunc("gomike_db_pool_wait_duration_seconds_total", "Total time spent waiting for a connection.",
+		poolStat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
@@ -1,12 +1,22 @@
 package models
 
+import "gorm.io/gorm"
+
 type Animal struct {
-	ID            string `gorm:"column:id;primaryKey"`
-	Name          string `gorm:"column:name;type:varchar(255);not null"`
-	Kind          string `gorm:"column:kind;type:varchar(100);not null"`
-	Age           int    `gorm:"column:age;type:int;not null"`
-	Description   string `gorm:"column:description;type:text"`
-	Breed         string `gorm:"column:breed;type:varchar(255)"`
-	Cloned        bool   `gorm:"column:cloned;type:boolean;default:false"`
+	ID          string `gorm:"column:id;primaryKey"`
+	Name        string `gorm:"column:name;type:varchar(255);not null"`
+	Kind        string `gorm:"column:kind;type:varchar(100);not null"`
+	Age         int    `gorm:"column:age;type:int;not null"`
+	Description string `gorm:"column:description;type:text"`
+	Breed       string `gorm:"column:breed;type:varchar(255)"`
+	Cloned      bool   `gorm:"column:cloned;type:boolean;default:false"`
+	// ClonedFromRef is the ID of the record of the same kind this one was cloned from. Writes check it,
+	// a clone whose origin was purged keeps Cloned with an empty ClonedFromRef.
 	ClonedFromRef string `gorm:"column:cloned_from_ref;type:varchar(100);default:''"`
+	// OwnerID is the ID of the person owning the animal, empty for none. It is changed through the
+	// /person/{id}/animals routes, the audit trail keeps the adoption history.
+	OwnerID string `gorm:"column:owner_id;type:varchar(100);index;default:''" readonly:"true"`
+	// DeletedAt is set when the record is moved to the trash, reads leave trashed records out
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" readonly:"true"`
+	Base
 }
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"maps"
+	"slices"
+	"time"
+)
+
+// AuditEntry records one change to a tracked record, it is written in the transaction of the change.
+// Entries form a hash chain: Hash covers the content of the entry and PrevHash, the Hash of entry Seq-1.
+// They are written Pending and linked after the change commits, Seq, PrevHash and Hash stay empty until then.
+type AuditEntry struct {
+	ID        string    `gorm:"column:id;primaryKey"`
+	Seq       int64     `gorm:"column:seq;index"`
+	Resource  string    `gorm:"column:resource;type:varchar(50);not null;index:idx_audit_record"`
+	RecordID  string    `gorm:"column:record_id;type:varchar(100);not null;index:idx_audit_record"`
+	Operation string    `gorm:"column:operation;type:varchar(20);not null"`
+	Actor     string    `gorm:"column:actor;type:varchar(255)"`
+	RequestID string    `gorm:"column:request_id;type:varchar(128)"`
+	Timestamp time.Time `gorm:"column:timestamp;not null"`
+	// Changes is the JSON encoding of a map from field name to FieldChange, kept as text so it hashes the same when read back
+	Changes  string `gorm:"column:changes;type:text"`
+	PrevHash string `gorm:"column:prev_hash;type:varchar(64)"`
+	Hash     string `gorm:"column:hash;type:varchar(64)"`
+	Pending  bool   `gorm:"column:pending;not null;default:false;index"`
+}
+
+// FieldChange holds the values of a field before and after a change, nil when the record did not exist
+type FieldChange struct {
+	Before any
+	After  any
+}
+
+// FieldChanges maps field names to how they changed
+type FieldChanges map[string]FieldChange
+
+// MarshalXML writes one Change element per field, by name, since encoding/xml cannot write maps
+func (c FieldChanges) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range slices.Sorted(maps.Keys(c)) {
+		change := xml.StartElement{Name: xml.Name{Local: "Change"}, Attr: []xml.Attr{{Name: xml.Name{Local: "Field"}, Value: field}}}
+		if err := e.EncodeToken(change); err != nil {
+			return err
+		}
+		for _, side := range []struct {
+			name  string
+			value any
+		}{{"Before", c[field].Before}, {"After", c[field].After}} {
+			if side.value == nil {
+				continue
+			}
+			text, err := xmlText(side.value)
+			if err != nil {
+				return err
+			}
+			if err := e.EncodeElement(text, xml.StartElement{Name: xml.Name{Local: side.name}}); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(change.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlText renders a value decoded from JSON as element text, anything but a string keeps its JSON form
+func xmlText(value any) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	text, err := json.Marshal(value)
+	return string(text), err
+}
+
+// AuditHead is the single row pointing at the newest sealed audit entry, the sealer locks it while linking entries
+type AuditHead struct {
+	ID   int    `gorm:"column:id;primaryKey"`
+	Seq  int64  `gorm:"column:seq;not null"`
+	Hash string `gorm:"column:hash;type:varchar(64)"`
+}
+
+// AuditCheckpoint vouches for the chain up to Seq with an Ed25519 signature over Seq, Hash and CreatedAt
+type AuditCheckpoint struct {
+	ID        string    `gorm:"column:id;primaryKey"`
+	Seq       int64     `gorm:"column:seq;not null;index"`
+	Hash      string    `gorm:"column:hash;type:varchar(64);not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	KeyID     string    `gorm:"column:key_id;type:varchar(64)"`
+	Signature string    `gorm:"column:signature;type:varchar(128);not null"`
+}
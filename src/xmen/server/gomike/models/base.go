@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Base holds the bookkeeping columns shared by the models, dbchef stamps them on every write but a purge
+type Base struct {
+	CreatedAt time.Time `gorm:"column:created_at;index" readonly:"true"`
+	UpdatedAt time.Time `gorm:"column:updated_at;index" readonly:"true"`
+	CreatedBy string    `gorm:"column:created_by;type:varchar(255)" readonly:"true"`
+	UpdatedBy string    `gorm:"column:updated_by;type:varchar(255)" readonly:"true"`
+}
+
+// Stamp implements dbchef.Stamped
+func (b *Base) Stamp(operation string, actor string, at time.Time) {
+	if operation == "create" {
+		b.CreatedAt = at
+		b.CreatedBy = actor
+	}
+	b.UpdatedAt = at
+	b.UpdatedBy = actor
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a POST sent with an Idempotency-Key header so a retry
+// gets it back instead of creating a duplicate. Status stays 0 while the first request is in flight.
+type IdempotencyKey struct {
+	ID          string    `gorm:"column:id;primaryKey"`
+	Key         string    `gorm:"column:key;type:varchar(255);not null"`
+	Principal   string    `gorm:"column:principal;type:varchar(255)"`
+	RequestHash string    `gorm:"column:request_hash;type:varchar(64);not null"`
+	Status      int       `gorm:"column:status;type:int;default:0"`
+	ContentType string    `gorm:"column:content_type;type:varchar(255)"`
+	Body        []byte    `gorm:"column:body;type:bytea"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;not null;index"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// OutboxCursor is the single row holding the Seq of the last audit entry the outbox relay published
+type OutboxCursor struct {
+	ID  int   `gorm:"column:id;primaryKey"`
+	Seq int64 `gorm:"column:seq;not null"`
+}
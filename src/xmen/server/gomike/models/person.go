@@ -1,12 +1,19 @@
 package models
 
+import "gorm.io/gorm"
+
 type Person struct {
-	ID            string `gorm:"column:id;primaryKey"`
-	Name          string `gorm:"column:name;type:varchar(100);not null"`
-	Kind          string `gorm:"column:kind;type:varchar(50);not null"`
-	Age           int    `gorm:"column:age;type:int"`
-	Description   string `gorm:"column:description;type:text"`
-	Nationality   string `gorm:"column:nationality;type:varchar(100)"`
-	Cloned        bool   `gorm:"column:cloned;default:false"`
+	ID          string `gorm:"column:id;primaryKey"`
+	Name        string `gorm:"column:name;type:varchar(100);not null"`
+	Kind        string `gorm:"column:kind;type:varchar(50);not null"`
+	Age         int    `gorm:"column:age;type:int"`
+	Description string `gorm:"column:description;type:text"`
+	Nationality string `gorm:"column:nationality;type:varchar(100)"`
+	Cloned      bool   `gorm:"column:cloned;default:false"`
+	// ClonedFromRef is the ID of the record of the same kind this one was cloned from. Writes check it,
+	// a clone whose origin was purged keeps Cloned with an empty ClonedFromRef.
 	ClonedFromRef string `gorm:"column:cloned_from_ref;type:varchar(100);default:''"`
+	// DeletedAt is set when the record is moved to the trash, reads leave trashed records out
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" readonly:"true"`
+	Base
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RecordVersion is a copy of a record as one write left it, versions of a record are numbered from 1.
+// A version with an empty Data marks the record being deleted.
+type RecordVersion struct {
+	ID        string    `gorm:"column:id;primaryKey"`
+	Resource  string    `gorm:"column:resource;type:varchar(50);not null;uniqueIndex:idx_version_record"`
+	RecordID  string    `gorm:"column:record_id;type:varchar(100);not null;uniqueIndex:idx_version_record"`
+	Version   int64     `gorm:"column:version;not null;uniqueIndex:idx_version_record"`
+	Operation string    `gorm:"column:operation;type:varchar(20);not null"`
+	Actor     string    `gorm:"column:actor;type:varchar(255)"`
+	RequestID string    `gorm:"column:request_id;type:varchar(128)"`
+	ValidFrom time.Time `gorm:"column:valid_from;not null"`
+	// Data is the JSON encoding of the record
+	Data string `gorm:"column:data;type:text"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Webhook is a subscription pushing change events to URL. Resources and Kinds are comma-separated
+// lists narrowing the events sent, empty for every event.
+type Webhook struct {
+	ID        string `gorm:"column:id;primaryKey" readonly:"true"`
+	URL       string `gorm:"column:url;type:varchar(2048);not null"`
+	Resources string `gorm:"column:resources;type:varchar(255)"`
+	Kinds     string `gorm:"column:kinds;type:varchar(255)"`
+	// Secret keys the HMAC signature of every delivery, it is only shown when the webhook is created
+	Secret string `gorm:"column:secret;type:varchar(128);not null"`
+	Active bool   `gorm:"column:active;not null"`
+	Base
+}
+
+// Delivery states of a WebhookDelivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery is one change event to push to one webhook, with the outcome of the latest attempt
+type WebhookDelivery struct {
+	ID             string     `gorm:"column:id;primaryKey"`
+	WebhookID      string     `gorm:"column:webhook_id;type:varchar(100);not null;uniqueIndex:idx_delivery_event"`
+	EventSeq       int64      `gorm:"column:event_seq;not null;uniqueIndex:idx_delivery_event"`
+	Kind           string     `gorm:"column:kind;type:varchar(20);not null"`
+	RequestID      string     `gorm:"column:request_id;type:varchar(128)"`
+	Payload        string     `gorm:"column:payload;type:text;not null"`
+	Status         string     `gorm:"column:status;type:varchar(20);not null;index:idx_delivery_due"`
+	Attempts       int        `gorm:"column:attempts;not null"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;not null;index:idx_delivery_due"`
+	LastStatusCode int        `gorm:"column:last_status_code"`
+	LastError      string     `gorm:"column:last_error;type:text"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at"`
+}
+
+// Requeue makes the delivery pending again with a fresh set of attempts, due at now
+func (d *WebhookDelivery) Requeue(now time.Time) {
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+}
+
+// WebhookCursor is the single row holding the Seq of the last audit entry turned into deliveries
+type WebhookCursor struct {
+	ID  int   `gorm:"column:id;primaryKey"`
+	Seq int64 `gorm:"column:seq;not null"`
+}
@@ -0,0 +1,73 @@
+package openapi
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// swaggerUI holds Swagger UI 5.18.2 (Apache-2.0), swagger-ui-bundle.js and swagger-ui.css from its dist
+// directory, bundled so the docs page works offline and only changes with gomike
+//
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+const docsPage = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>%[1]s</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="%[2]s/swagger-ui.css">
+  </head>
+  <body>
+    <div id="swagger-ui"></div>
+    <script src="%[2]s/swagger-ui-bundle.js"></script>
+    <script>
+      window.ui = SwaggerUIBundle({url: "%[3]s", dom_id: "#swagger-ui", deepLinking: true, validatorUrl: null});
+    </script>
+  </body>
+</html>
+`
+
+// SpecHandler serves the document returned by build
+func SpecHandler(build func() ([]byte, error)) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			spec, err := build()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to build OpenAPI document: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write(spec)
+		},
+	)
+}
+
+// DocsHandler serves a Swagger UI page rendering the document at specURL, with its scripts and styles
+// loaded from assetsURL where AssetsHandler is mounted
+func DocsHandler(title, specURL, assetsURL string) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, docsPage, html.EscapeString(title), html.EscapeString(assetsURL), html.EscapeString(specURL))
+		},
+	)
+}
+
+// AssetsHandler serves the bundled Swagger UI files under prefix, e.g. /docs/swagger-ui/swagger-ui.css
+func AssetsHandler(prefix string) http.Handler {
+	files := http.FileServerFS(swaggerUI)
+	return http.StripPrefix(prefix, http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			// The files are pinned in the binary, a new version only comes with a new gomike
+			w.Header().Set("Cache-Control", "public, max-age=86400")
+			req.URL.Path = "swagger-ui/" + req.URL.Path
+			files.ServeHTTP(w, req)
+		},
+	))
+}
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocsPageLoadsBundledAssets(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /docs", DocsHandler("gomike", "/openapi.json", "/docs/assets"))
+	mux.Handle("GET /docs/assets/", AssetsHandler("/docs/assets/"))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(page), "https://") {
+		t.Errorf("docs page loads something from the network:\n%s", page)
+	}
+
+	for path, contentType := range map[string]string{
+		"/docs/assets/swagger-ui-bundle.js": "text/javascript",
+		"/docs/assets/swagger-ui.css":       "text/css",
+	} {
+		if !strings.Contains(string(page), path) {
+			t.Errorf("docs page does not reference %s", path)
+		}
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), contentType) {
+			t.Errorf("GET %s: %d %s, want 200 %s", path, resp.StatusCode, resp.Header.Get("Content-Type"), contentType)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// OpenAPI 3.1 documents generated from the registered routes and model structs
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	xChain "gomike/chain"
+	xCodec "gomike/codec"
+)
+
+// Operation documents a route, it is attached with xChain.WithMeta
+type Operation struct {
+	OperationID string
+	Summary     string
+	Tags        []string
+	// RequestBody is a model value whose schema documents the JSON request body
+	RequestBody any
+	// RequestMediaTypes narrows the media types the request body is accepted in
+	RequestMediaTypes []string
+	Query             []Param
+	Responses         []Response
+	// Errors lists the statuses answered with a problem+json body
+	Errors []int
+	// JSONOnly marks routes that do not take part in content negotiation
+	JSONOnly bool
+}
+
+// Param documents a query parameter
+type Param struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+type Response struct {
+	Status      int
+	Description string
+	// ContentType defaults to every negotiable media type when Body is set and text/plain otherwise
+	ContentType string
+	// MediaTypes narrows the negotiable media types a Body is answered in
+	MediaTypes []string
+	// Body is a model value (or slice of model values) whose schema documents the response
+	Body any
+}
+
+var pathParam = regexp.MustCompile(`\{([^}.]+)(?:\.\.\.)?\}`)
+
+type builder struct {
+	schemas map[string]any
+}
+
+// Build renders the OpenAPI document for every route carrying an Operation
+func Build(title, version string, routes []xChain.RouteInfo) ([]byte, error) {
+	b := &builder{schemas: map[string]any{
+		"Problem": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"type":      map[string]any{"type": "string"},
+				"title":     map[string]any{"type": "string"},
+				"status":    map[string]any{"type": "integer"},
+				"detail":    map[string]any{"type": "string"},
+				"instance":  map[string]any{"type": "string"},
+				"requestId": map[string]any{"type": "string"},
+			},
+		},
+	}}
+
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		op, ok := route.Meta.(Operation)
+		if !ok {
+			continue
+		}
+		method, path, found := strings.Cut(route.Pattern, " ")
+		if !found {
+			continue
+		}
+		path = pathParam.ReplaceAllString(path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		idempotent := method == http.MethodPost && slices.Contains(route.Middlewares, "idempotency")
+		paths[path][strings.ToLower(method)] = b.operation(op, path, !slices.Contains(route.Middlewares, "auth"), idempotent)
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"basicAuth": map[string]any{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []any{map[string]any{"basicAuth": []string{}}},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (b *builder) operation(op Operation, path string, public, idempotent bool) map[string]any {
+	out := map[string]any{
+		"operationId": op.OperationID,
+		"summary":     op.Summary,
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+	if public {
+		out["security"] = []any{}
+	}
+
+	parameters := []any{}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, param := range op.Query {
+		schema := map[string]any{"type": "string"}
+		if len(param.Enum) > 0 {
+			schema["enum"] = param.Enum
+		}
+		parameters = append(parameters, map[string]any{
+			"name":        param.Name,
+			"in":          "query",
+			"description": param.Description,
+			"schema":      schema,
+		})
+	}
+	errors := op.Errors
+	if idempotent {
+		parameters = append(parameters, map[string]any{
+			"name":        "Idempotency-Key",
+			"in":          "header",
+			"description": "Retries sent with the same key replay the first response instead of running again",
+			"schema":      map[string]any{"type": "string", "maxLength": 128},
+		})
+		for _, status := range []int{http.StatusConflict, http.StatusUnprocessableEntity} {
+			documented := slices.ContainsFunc(op.Responses, func(resp Response) bool { return resp.Status == status })
+			if !documented && !slices.Contains(errors, status) {
+				errors = append(slices.Clone(errors), status)
+			}
+		}
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+
+	if op.RequestBody != nil {
+		content := b.content(op.RequestBody, op.JSONOnly)
+		if len(op.RequestMediaTypes) > 0 {
+			content = map[string]any{}
+			for _, mediaType := range op.RequestMediaTypes {
+				content[mediaType] = map[string]any{"schema": b.ref(op.RequestBody)}
+			}
+		}
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	responses := map[string]any{}
+	for _, resp := range op.Responses {
+		description := resp.Description
+		if description == "" {
+			description = http.StatusText(resp.Status)
+		}
+		entry := map[string]any{"description": description}
+		switch {
+		case resp.ContentType != "":
+			schema := map[string]any{"type": "string"}
+			if resp.Body != nil {
+				schema = b.ref(resp.Body)
+			}
+			entry["content"] = map[string]any{resp.ContentType: map[string]any{"schema": schema}}
+		case resp.Body != nil && len(resp.MediaTypes) > 0:
+			content := map[string]any{}
+			for _, mediaType := range resp.MediaTypes {
+				content[mediaType] = map[string]any{"schema": b.ref(resp.Body)}
+			}
+			entry["content"] = content
+		case resp.Body != nil:
+			entry["content"] = b.content(resp.Body, op.JSONOnly)
+		case resp.Status != http.StatusNoContent:
+			entry["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+		}
+		responses[strconv.Itoa(resp.Status)] = entry
+	}
+	for _, status := range errors {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content": map[string]any{
+				"application/problem+json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+			},
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+// content lists the media types a typed body can be exchanged in
+func (b *builder) content(model any, jsonOnly bool) map[string]any {
+	schema := b.ref(model)
+	if jsonOnly {
+		return map[string]any{"application/json": map[string]any{"schema": schema}}
+	}
+	content := map[string]any{}
+	for _, mediaType := range xCodec.Supported() {
+		content[mediaType] = map[string]any{"schema": schema}
+	}
+	return content
+}
+
+// ref registers the schema of model under components and returns a reference to it
+func (b *builder) ref(model any) map[string]any {
+	t := indirect(reflect.TypeOf(model))
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		return map[string]any{"type": "array", "items": b.ref(reflect.New(t.Elem()).Elem().Interface())}
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" {
+		return typeSchema(t)
+	}
+	if _, ok := b.schemas[t.Name()]; !ok {
+		b.schemas[t.Name()] = schemaFor(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+}
@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"database/sql"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	xSession "gomike/session"
+)
+
+var varcharLength = regexp.MustCompile(`^(?i)(?:varchar|character varying|char)\((\d+)\)$`)
+
+// schemaFor describes a model struct, honouring json tags for names and gorm tags for constraints
+func schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	properties := map[string]any{}
+	required := []string{}
+	collectFields(t, properties, &required)
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, jsonSkip := jsonFieldName(field)
+		if jsonSkip {
+			continue
+		}
+		// Embedded structs without a json name are flattened by encoding/json
+		if field.Anonymous && jsonName == "" && indirect(field.Type).Kind() == reflect.Struct {
+			collectFields(indirect(field.Type), properties, required)
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		property := typeSchema(field.Type)
+		gormTag := xSession.GormSettings(field.Tag.Get("gorm"))
+		if column, ok := gormTag["column"]; ok {
+			property["x-gorm-column"] = column
+		}
+		if columnType, ok := gormTag["type"]; ok {
+			property["x-gorm-type"] = columnType
+			if match := varcharLength.FindStringSubmatch(columnType); match != nil {
+				length, _ := strconv.Atoi(match[1])
+				property["maxLength"] = length
+			}
+		}
+		if def, ok := gormTag["default"]; ok {
+			property["default"] = defaultValue(field.Type, def)
+		}
+		if _, ok := gormTag["primarykey"]; ok {
+			property["x-primary-key"] = true
+		}
+		if _, ok := gormTag["not null"]; ok {
+			*required = append(*required, jsonName)
+		}
+		if field.Tag.Get("readonly") == "true" {
+			property["readOnly"] = true
+		}
+		properties[jsonName] = property
+	}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		schema := typeSchema(t.Elem())
+		if kind, ok := schema["type"].(string); ok {
+			schema["type"] = []string{kind, "null"}
+		}
+		return schema
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	// sql.NullTime and types built on it, such as gorm.DeletedAt, encode as a time or null
+	if t.Kind() == reflect.Struct && t.ConvertibleTo(reflect.TypeOf(sql.NullTime{})) {
+		return map[string]any{"type": []string{"string", "null"}, "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return schemaFor(t)
+	}
+	return map[string]any{}
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func defaultValue(t reflect.Type, raw string) any {
+	raw = strings.Trim(raw, "'")
+	switch indirect(t).Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	}
+	return raw
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
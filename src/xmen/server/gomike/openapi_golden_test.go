@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+
+	xOpenAPI "gomike/openapi"
+)
+
+var update = flag.Bool("update", false, "regenerate openapi/openapi.golden.json from the registered routes")
+
+const openAPIGolden = "openapi/openapi.golden.json"
+
+// TestOpenAPIGolden builds the document from the registered routes and compares it byte for byte with the
+// checked-in golden file, run `go test -run TestOpenAPIGolden . -update` to accept a change to the API
+func TestOpenAPIGolden(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	api := registerRoutes(http.NewServeMux(), nil, logger, accessLogConfig{}, idempotencyConfig{})
+	spec, err := xOpenAPI.Build(apiTitle, apiVersion, api.Routes())
+	if err != nil {
+		t.Fatalf("building the OpenAPI document: %v", err)
+	}
+	spec = append(spec, '\n')
+
+	if *update {
+		if err := os.WriteFile(openAPIGolden, spec, 0o644); err != nil {
+			t.Fatalf("writing %s: %v", openAPIGolden, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(openAPIGolden)
+	if err != nil {
+		t.Fatalf("reading %s: %v", openAPIGolden, err)
+	}
+	if !bytes.Equal(want, spec) {
+		t.Errorf("OpenAPI document drifted from %s at byte %d, run `go test -run TestOpenAPIGolden . -update` to regenerate it", openAPIGolden, firstDifference(want, spec))
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
@@ -0,0 +1,25 @@
+// Outbound HTTP calls made on behalf of an incoming request
+
+package outbound
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	xRouter "gomike/router"
+	xTracing "gomike/tracing"
+)
+
+// NewRequest builds an outbound request that carries the request ID and trace context of ctx
+func NewRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if requestID := xRouter.RequestID(ctx); requestID != "" {
+		req.Header.Set(xRouter.RequestIDHeader, requestID)
+	}
+	xTracing.Inject(xTracing.SpanContextFromContext(ctx), req.Header)
+	return req, nil
+}
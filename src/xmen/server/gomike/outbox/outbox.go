@@ -0,0 +1,33 @@
+// Transactional outbox: the audit trail, written in the transaction of every change, is the outbox. A relay
+// publishes its sealed entries to a sink once they are committed, as the same change events SSE and webhooks
+// send and in the same Seq order.
+
+package outbox
+
+import (
+	"strings"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const cursorID = 1
+
+// EnsureCursor creates the row recording how far the change feed was published, sinks only hear about
+// changes made after the first start
+func EnsureCursor(dbSession *xDb.DBSession) error {
+	cursor := xModels.OutboxCursor{}
+	err := dbSession.ReadRecord(map[string]interface{}{"id": cursorID}, &cursor)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(strings.ToLower(err.Error()), "record not found") {
+		return err
+	}
+	seq, err := xSession.LatestEventSeq(dbSession)
+	if err != nil {
+		return err
+	}
+	return dbSession.CreateRecord(&xModels.OutboxCursor{ID: cursorID, Seq: seq})
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	relayInterval  = time.Second
+	relayBatchSize = 500
+	publishTimeout = 10 * time.Second
+)
+
+// Relay publishes the change feed to sink in Seq order until the process exits. The cursor only moves past
+// an event once sink accepted it, so a crash in between sends it again. An event that fails holds back the
+// ones after it until the next round, keeping them in order.
+func Relay(log *slog.Logger, dbSession *xDb.DBSession, sink Sink) {
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for {
+			read, err := relayBatch(dbSession, sink)
+			if err != nil {
+				log.Error("Failed to relay change events", slog.String("error", err.Error()))
+				break
+			}
+			if read < relayBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// relayBatch publishes the next batch of change events and moves the cursor past the published ones, in one
+// transaction so another gomike waits for it rather than publishing them twice. It returns how many it read.
+func relayBatch(dbSession *xDb.DBSession, sink Sink) (int, error) {
+	read := 0
+	var failed error
+	err := dbSession.Transaction(func(tx *xDb.DBSession) error {
+		cursor := xModels.OutboxCursor{}
+		if err := tx.ReadRecordForUpdate(map[string]interface{}{"id": cursorID}, &cursor); err != nil {
+			return fmt.Errorf("outbox cursor: %w", err)
+		}
+		entries, err := xSession.EventsAfter(tx, nil, cursor.Seq, relayBatchSize)
+		if err != nil {
+			return err
+		}
+		read = len(entries)
+		published := cursor.Seq
+		for _, entry := range entries {
+			// A corrupt entry is reported by audit verify, it must not hold the other events back
+			if event, err := xRouter.NewChangeEvent(entry); err == nil {
+				ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+				err = sink.Publish(ctx, event)
+				cancel()
+				if err != nil {
+					failed = fmt.Errorf("event %d: %w", entry.Seq, err)
+					break
+				}
+			}
+			published = entry.Seq
+		}
+		if published == cursor.Seq {
+			return nil
+		}
+		cursor.Seq = published
+		return tx.UpdateRecordColumns(&cursor, "seq")
+	})
+	if err != nil {
+		return read, err
+	}
+	return read, failed
+}
@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	xRouter "gomike/router"
+)
+
+// Sink publishes change events. Publish returns once the event is safely handed over, the relay sends it
+// again when it fails. Seq only grows, sinks and their consumers can use it to drop duplicates.
+type Sink interface {
+	Publish(ctx context.Context, event xRouter.ChangeEvent) error
+}
+
+// Broker is the part of a NATS or Kafka client a BrokerSink needs, an adapter around the client library
+// implements it
+type Broker interface {
+	Publish(ctx context.Context, topic string, key []byte, value []byte) error
+}
+
+// BrokerSink publishes every event to Topic keyed by its resource and record ID, so brokers partitioning by
+// key keep the events of one record in order
+type BrokerSink struct {
+	Broker Broker
+	Topic  string
+}
+
+func (s BrokerSink) Publish(ctx context.Context, event xRouter.ChangeEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.Broker.Publish(ctx, s.Topic, []byte(event.Resource+"/"+event.RecordID), value)
+}
+
+// WriterSink writes every event as a line of JSON
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event xRouter.ChangeEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// An event only counts as published once it is on disk
+	if file, ok := s.w.(*os.File); ok && file != os.Stdout {
+		return file.Sync()
+	}
+	return nil
+}
+
+// SinkFromEnv reads GOMIKE_OUTBOX_SINK, "stdout" or "file:<path>". It returns nil when it is not set,
+// broker sinks are wired in code.
+func SinkFromEnv() (Sink, error) {
+	raw := os.Getenv("GOMIKE_OUTBOX_SINK")
+	switch {
+	case raw == "":
+		return nil, nil
+	case raw == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(raw, "file:"):
+		path := strings.TrimPrefix(raw, "file:")
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("GOMIKE_OUTBOX_SINK: %w", err)
+		}
+		return NewWriterSink(file), nil
+	}
+	return nil, fmt.Errorf("invalid GOMIKE_OUTBOX_SINK %q, expected stdout or file:<path>", raw)
+}
@@ -2,7 +2,6 @@ package router
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -20,26 +19,30 @@ func GetAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
 			errResponse := "Animal ID not provided in the URL"
-			log.Error("Animal ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Animal ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
 				Message:    []byte(errResponse),
 			}
 		}
 
-		log.Info("Got animal ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got animal ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
-		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, reqObjID)
+		if asOf := queryParams(reqCtx).Get("as_of"); asOf != "" {
+			return readAsOf[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID, asOf)
+		}
+
+		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Error("Animal not found", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Error("Animal not found", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Animal  with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
 					Message:    []byte(errResponse),
 				}
 			}
-			log.Error("Error retrieving animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -47,20 +50,10 @@ func GetAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 			}
 		}
 
-		objDetails, err := json.Marshal(animalPtr)
-		if err != nil {
-			log.Error("Failed to marshal animal details", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
-			errResponse := fmt.Sprintf("Failed to marshal animal details: %s", err.Error())
-			return RespDetail{
-				Statuscode: http.StatusInternalServerError,
-				Message:    []byte(errResponse),
-			}
-		}
-		log.Info("Animal details retrieved successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Animal details retrieved successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusOK,
-			Message:    objDetails,
-			Type:       "application/json",
+			Body:       animalPtr,
 		}
 	}
 }
@@ -68,7 +61,7 @@ func GetAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Animal ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Animal ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Animal ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -76,11 +69,11 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Got animal ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got animal ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -88,7 +81,7 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -96,10 +89,13 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, reqObjID)
+		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Info("Animal not found, Creating a new one", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				if resp, ok := trashedConflict[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID); ok {
+					return resp
+				}
+				log.Info("Animal not found, Creating a new one", slog.String("request-id", RequestID(reqCtx)))
 				// If the animal is not found, create a new animal with the provided ID
 				animal := xModels.Animal{
 					ID:            reqObjID,
@@ -107,18 +103,21 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 					Cloned:        false,
 					ClonedFromRef: "",
 				} // Create a new animal instance with the ID from the URL
-				err = json.Unmarshal(body, &animal)
+				err = decodeBody(reqCtx, body, &animal)
 				if err != nil {
-					log.Error("Failed to unmarshal request body into animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+					log.Error("Failed to unmarshal request body into animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 					errResponse := fmt.Sprintf("Failed to unmarshal request body into animal: %s", err.Error())
 					return RespDetail{
 						Statuscode: http.StatusBadRequest,
 						Message:    []byte(errResponse),
 					}
 				}
-				err = xSession.CreateRecord(dbSession, animal)
+				err = xSession.CreateRecord(dbSession.WithContext(reqCtx), animal)
 				if err != nil {
-					log.Error("Failed to create animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+					if resp, ok := cloneRefRefusal(err); ok {
+						return resp
+					}
+					log.Error("Failed to create animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 					errResponse := fmt.Sprintf("Failed to create animal: %s", err.Error())
 					return RespDetail{
 						Statuscode: http.StatusInternalServerError,
@@ -126,12 +125,12 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 					}
 				}
 
-				log.Info("New animal created successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Info("New animal created successfully", slog.String("request-id", RequestID(reqCtx)))
 				return RespDetail{
 					Statuscode: http.StatusNoContent,
 				}
 			}
-			log.Error("Error retrieving animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving animal with ID %s: %s", reqObjID, err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -139,10 +138,10 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Updating existing animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = json.Unmarshal(body, animalPtr)
+		log.Info("Updating existing animal", slog.String("request-id", RequestID(reqCtx)))
+		err = decodeBody(reqCtx, body, animalPtr)
 		if err != nil {
-			log.Error("Failed to unmarshal request body into animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to unmarshal request body into animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -150,9 +149,12 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		err = xSession.UpdateRecord(dbSession, *animalPtr)
+		err = xSession.UpdateRecord(dbSession.WithContext(reqCtx), *animalPtr)
 		if err != nil {
-			log.Error("Failed to update animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to update animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to update animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -160,7 +162,7 @@ func UpdateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Animal updated successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Animal updated successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusNoContent,
 		}
@@ -171,7 +173,7 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -180,7 +182,7 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 		}
 
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -195,10 +197,10 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			ClonedFromRef: "",
 		} // Create a new animal instance
 
-		log.Info("Creating a new animal with ID", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", animal.ID))
-		err = json.Unmarshal(body, &animal)
+		log.Info("Creating a new animal with ID", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", animal.ID))
+		err = decodeBody(reqCtx, body, &animal)
 		if err != nil {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -206,9 +208,12 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		err = xSession.CreateRecord(dbSession, animal)
+		err = xSession.CreateRecord(dbSession.WithContext(reqCtx), animal)
 		if err != nil {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := fmt.Sprintf("Failed to create animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -216,7 +221,7 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Error("New Animal created successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Error("New Animal created successfully", slog.String("request-id", RequestID(reqCtx)))
 		res := fmt.Sprintf("Animal with ID %s added", animal.ID)
 		return RespDetail{
 			Statuscode: http.StatusCreated,
@@ -229,7 +234,7 @@ func CreateAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Animal ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Animal ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Animal ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -237,11 +242,11 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 			}
 		}
 
-		log.Info("Got animal ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got animal ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -250,7 +255,7 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 		}
 
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -258,17 +263,17 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 			}
 		}
 
-		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, reqObjID)
+		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Warn("Patch method called on non-existing animal, only allowed on existing records", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Warn("Patch method called on non-existing animal, only allowed on existing records", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Patch method is only allowed on existing records. Animal with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
 					Message:    []byte(errResponse),
 				}
 			}
-			log.Error("Error retrieving animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving animal with ID %s: %s", reqObjID, err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -276,9 +281,9 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 			}
 		}
 
-		err = json.Unmarshal(body, animalPtr)
+		err = decodeBody(reqCtx, body, animalPtr)
 		if err != nil {
-			log.Error("Failed to unmarshal request body into animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to unmarshal request body into animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -286,10 +291,13 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 			}
 		}
 
-		log.Info("Patching existing animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = xSession.UpdateRecord(dbSession, *animalPtr)
+		log.Info("Patching existing animal", slog.String("request-id", RequestID(reqCtx)))
+		err = xSession.UpdateRecord(dbSession.WithContext(reqCtx), *animalPtr)
 		if err != nil {
-			log.Error("Failed to update animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to update animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to update animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -297,7 +305,7 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 			}
 		}
 
-		log.Info("Animal patched successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Animal patched successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusNoContent,
 		}
@@ -308,7 +316,7 @@ func PatchAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 func DeleteAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Animal ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Animal ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Animal ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -316,19 +324,23 @@ func DeleteAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Got animal ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got animal ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
+
+		if queryParams(reqCtx).Get("force") == "true" {
+			return forceDelete[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID)
+		}
 
-		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession, reqObjID)
+		animalPtr, err := xSession.ReadRecord[xModels.Animal](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Error("Animal ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Error("Animal ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Animal with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
 					Message:    []byte(errResponse),
 				}
 			}
-			log.Error("Error retrieving animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -336,10 +348,13 @@ func DeleteAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Deleting animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = xSession.DeleteRecord(dbSession, *animalPtr)
+		log.Info("Deleting animal", slog.String("request-id", RequestID(reqCtx)))
+		err = xSession.DeleteRecord(dbSession.WithContext(reqCtx), *animalPtr)
 		if err != nil {
-			log.Error("Failed to delete animal", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to delete animal", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to delete animal: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -348,7 +363,7 @@ func DeleteAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 		}
 
 		res := fmt.Sprintf("Animal with ID %s deleted", reqObjID)
-		log.Info("Animal deleted successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Animal deleted successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusOK,
 			Message:    []byte(res),
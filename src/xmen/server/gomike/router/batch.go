@@ -0,0 +1,313 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	xCodec "gomike/codec"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+const maxBatchOperations = 1000
+
+// BatchRequest is an ordered list of operations run through the same router functions as single requests
+type BatchRequest struct {
+	Operations []BatchOperation
+}
+
+// BatchOperation is one sub-request. Ref names it so later operations can use the ID it created
+// or addressed as ${Ref} in their path or body.
+type BatchOperation struct {
+	Ref    string `json:",omitempty"`
+	Method string
+	Path   string
+	Body   map[string]any `json:",omitempty"`
+}
+
+type BatchResponse struct {
+	Atomic bool
+	// Committed is false when an atomic batch was rolled back
+	Committed bool
+	Results   []BatchResult
+}
+
+type BatchResult struct {
+	Ref     string `json:",omitempty"`
+	ID      string `json:",omitempty"`
+	Status  int
+	Body    any    `json:",omitempty"`
+	Message string `json:",omitempty"`
+	Error   string `json:",omitempty"`
+}
+
+// BatchRoutes resolves the method and path of a batch operation to the router function serving it
+type BatchRoutes struct {
+	mux *http.ServeMux
+}
+
+func NewBatchRoutes() *BatchRoutes {
+	return &BatchRoutes{mux: http.NewServeMux()}
+}
+
+type batchSlotKey struct{}
+
+// batchSlot carries the outcome of a router function back out of the mux
+type batchSlot struct {
+	served bool
+	objID  string
+	resp   RespDetail
+}
+
+// Handle makes routerFunc reachable from batches under pattern, the pattern syntax is the one of http.ServeMux
+func (b *BatchRoutes) Handle(pattern string, routerFunc func(context.Context, string, io.ReadCloser) RespDetail) {
+	b.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
+		slot := req.Context().Value(batchSlotKey{}).(*batchSlot)
+		slot.served = true
+		slot.objID = req.PathValue("reqObjID")
+		slot.resp = routerFunc(WithPathValues(req.Context(), req), slot.objID, req.Body)
+	})
+}
+
+// batchStatusWriter records the status the mux answers with when no route matches
+type batchStatusWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *batchStatusWriter) Header() http.Header { return w.header }
+
+func (w *batchStatusWriter) Write(data []byte) (int, error) { return len(data), nil }
+
+func (w *batchStatusWriter) WriteHeader(status int) { w.status = status }
+
+func (b *BatchRoutes) dispatch(ctx context.Context, method, path string, body []byte) (*batchSlot, error) {
+	slot := &batchSlot{}
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, batchSlotKey{}, slot), method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(WithQuery(WithRequestCodec(req.Context(), xCodec.JSON{}), req.URL.Query()))
+	w := &batchStatusWriter{header: http.Header{}, status: http.StatusOK}
+	b.mux.ServeHTTP(w, req)
+	if !slot.served {
+		return nil, &batchRouteError{status: w.status, method: method, path: path}
+	}
+	return slot, nil
+}
+
+type batchRouteError struct {
+	status int
+	method string
+	path   string
+}
+
+func (e *batchRouteError) Error() string {
+	return fmt.Sprintf("No batch route for %s %s", e.method, e.path)
+}
+
+var (
+	batchRef       = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+	errBatchFailed = errors.New("batch operation failed")
+)
+
+func Batch(dbSession *xDb.DBSession, log *slog.Logger, routes *BatchRoutes) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		atomic := false
+		if raw := queryParams(reqCtx).Get("atomic"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				errResponse := fmt.Sprintf("atomic must be true or false, got %q", raw)
+				return RespDetail{
+					Statuscode: http.StatusBadRequest,
+					Message:    []byte(errResponse),
+				}
+			}
+			atomic = parsed
+		}
+
+		body, err := io.ReadAll(reqBody)
+		if err != nil {
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		if len(body) == 0 {
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte("Request body is empty"),
+			}
+		}
+
+		batch := BatchRequest{}
+		if err := decodeBody(reqCtx, body, &batch); err != nil {
+			log.Error("Failed to unmarshal request body into batch", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to unmarshal request body into batch: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(errResponse),
+			}
+		}
+		if err := validateBatch(batch); err != nil {
+			log.Error("Invalid batch", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(err.Error()),
+			}
+		}
+
+		resp := BatchResponse{Atomic: atomic, Committed: true, Results: make([]BatchResult, len(batch.Operations))}
+		if !atomic {
+			runBatch(reqCtx, routes, batch, resp.Results, false)
+		} else {
+			err = xSession.Transaction(dbSession.WithContext(reqCtx), func(tx *xDb.DBSession) error {
+				return runBatch(xDb.ContextWithTransaction(reqCtx, tx), routes, batch, resp.Results, true)
+			})
+			if err != nil {
+				resp.Committed = false
+				log.Error("Atomic batch rolled back", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+				return RespDetail{
+					Statuscode: http.StatusUnprocessableEntity,
+					Body:       resp,
+				}
+			}
+		}
+
+		log.Info("Batch finished", slog.String("request-id", RequestID(reqCtx)), slog.Int("operations", len(batch.Operations)), slog.Bool("atomic", atomic))
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       resp,
+		}
+	}
+}
+
+func validateBatch(batch BatchRequest) error {
+	if len(batch.Operations) == 0 {
+		return errors.New("Batch holds no operations")
+	}
+	if len(batch.Operations) > maxBatchOperations {
+		return fmt.Errorf("Batch holds %d operations, at most %d are allowed", len(batch.Operations), maxBatchOperations)
+	}
+	refs := map[string]bool{}
+	for i, op := range batch.Operations {
+		if op.Method == "" || op.Path == "" {
+			return fmt.Errorf("Operation %d needs a Method and a Path", i)
+		}
+		if op.Ref == "" {
+			continue
+		}
+		if refs[op.Ref] {
+			return fmt.Errorf("Ref %q is used by more than one operation", op.Ref)
+		}
+		refs[op.Ref] = true
+	}
+	return nil
+}
+
+// runBatch runs the operations in order, filling results. When stopOnError is set the first failure
+// marks the remaining operations as skipped and is returned so the transaction rolls back.
+func runBatch(ctx context.Context, routes *BatchRoutes, batch BatchRequest, results []BatchResult, stopOnError bool) error {
+	ids := map[string]string{}
+	for i, op := range batch.Operations {
+		result := runBatchOperation(ctx, routes, op, ids)
+		results[i] = result
+		if result.Status < http.StatusBadRequest || !stopOnError {
+			continue
+		}
+		for j := i + 1; j < len(batch.Operations); j++ {
+			results[j] = BatchResult{
+				Ref:    batch.Operations[j].Ref,
+				Status: http.StatusFailedDependency,
+				Error:  "Skipped because an earlier operation of the atomic batch failed",
+			}
+		}
+		return fmt.Errorf("%w: operation %d answered %d", errBatchFailed, i, result.Status)
+	}
+	return nil
+}
+
+func runBatchOperation(ctx context.Context, routes *BatchRoutes, op BatchOperation, ids map[string]string) BatchResult {
+	result := BatchResult{Ref: op.Ref}
+	fail := func(status int, err error) BatchResult {
+		result.Status = status
+		result.Error = err.Error()
+		return result
+	}
+
+	path, err := resolveBatchRefs(op.Path, ids)
+	if err != nil {
+		return fail(http.StatusBadRequest, err)
+	}
+	// A created record is given its ID up front, so later operations can refer to it
+	if op.Ref != "" && op.Method == http.MethodPost && op.Body != nil {
+		if _, ok := op.Body["ID"]; !ok {
+			op.Body["ID"] = uuid.New().String()
+		}
+	}
+	var body []byte
+	if op.Body != nil {
+		body, err = json.Marshal(op.Body)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		resolved, err := resolveBatchRefs(string(body), ids)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		body = []byte(resolved)
+	}
+
+	slot, err := routes.dispatch(ctx, op.Method, path, body)
+	var routeErr *batchRouteError
+	if errors.As(err, &routeErr) {
+		return fail(routeErr.status, err)
+	}
+	if err != nil {
+		return fail(http.StatusBadRequest, err)
+	}
+
+	result.Status = slot.resp.Statuscode
+	if result.Status >= http.StatusBadRequest {
+		result.Error = string(slot.resp.Message)
+		return result
+	}
+	result.Body = slot.resp.Body
+	if slot.resp.Body == nil {
+		result.Message = string(slot.resp.Message)
+	}
+	result.ID = slot.objID
+	if id, ok := op.Body["ID"].(string); ok {
+		result.ID = id
+	}
+	if op.Ref != "" {
+		ids[op.Ref] = result.ID
+	}
+	return result
+}
+
+func resolveBatchRefs(s string, ids map[string]string) (string, error) {
+	var missing error
+	resolved := batchRef.ReplaceAllStringFunc(s, func(match string) string {
+		ref := batchRef.FindStringSubmatch(match)[1]
+		id, ok := ids[ref]
+		if !ok && missing == nil {
+			missing = fmt.Errorf("Ref %q does not name an earlier successful operation", ref)
+		}
+		return id
+	})
+	return resolved, missing
+}
@@ -0,0 +1,63 @@
+package router
+
+import (
+	"encoding/json"
+	"time"
+
+	xModels "gomike/models"
+)
+
+// Kinds of change reported by the change feed, a clone is the creation of a record cloned from another
+const (
+	EventCreate  = "create"
+	EventUpdate  = "update"
+	EventDelete  = "delete"
+	EventRestore = "restore"
+	EventPurge   = "purge"
+	EventClone   = "clone"
+)
+
+// EventKinds lists every kind of change the feed reports
+var EventKinds = []string{EventCreate, EventUpdate, EventDelete, EventRestore, EventPurge, EventClone}
+
+// ChangeEvent is one change to a person or animal as sent by the change feed, Seq identifies it for resuming
+type ChangeEvent struct {
+	Seq       int64
+	Kind      string
+	Resource  string
+	RecordID  string
+	Actor     string
+	RequestID string
+	Timestamp time.Time
+	Changes   xModels.FieldChanges
+}
+
+// NewChangeEvent describes the change an audit entry records
+func NewChangeEvent(entry xModels.AuditEntry) (ChangeEvent, error) {
+	changes := xModels.FieldChanges{}
+	if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+		return ChangeEvent{}, err
+	}
+	kind := entry.Operation
+	if kind == EventCreate && isClone(changes) {
+		kind = EventClone
+	}
+	return ChangeEvent{
+		Seq:       entry.Seq,
+		Kind:      kind,
+		Resource:  entry.Resource,
+		RecordID:  entry.RecordID,
+		Actor:     entry.Actor,
+		RequestID: entry.RequestID,
+		Timestamp: entry.Timestamp,
+		Changes:   changes,
+	}, nil
+}
+
+func isClone(changes xModels.FieldChanges) bool {
+	if cloned, ok := changes["Cloned"].After.(bool); ok && cloned {
+		return true
+	}
+	ref, _ := changes["ClonedFromRef"].After.(string)
+	return ref != ""
+}
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	xCodec "gomike/codec"
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+func ExportPersons(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return exportRecords[xModels.Person](reqCtx, dbSession, log, "person")
+	}
+}
+
+func ExportAnimals(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return exportRecords[xModels.Animal](reqCtx, dbSession, log, "animal")
+	}
+}
+
+// exportRecords streams every record matching the query filters, one database row at a time
+func exportRecords[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string) RespDetail {
+	conditions, err := xSession.Conditions[T](queryParams(reqCtx))
+	if err != nil {
+		log.Error("Invalid export filter", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(err.Error()),
+		}
+	}
+
+	c := responseCodec(reqCtx)
+	if _, ok := xCodec.NewListWriter(c, io.Discard, reflect.TypeFor[T]()); !ok {
+		errResponse := fmt.Sprintf("Exports are available as %s, %s or %s", xCodec.NDJSON{}.MediaType(), xCodec.CSV{}.MediaType(), xCodec.JSON{}.MediaType())
+		return RespDetail{
+			Statuscode: http.StatusNotAcceptable,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	log.Info("Exporting records", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("media-type", c.MediaType()))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Stream: func(w io.Writer) error {
+			list, _ := xCodec.NewListWriter(c, w, reflect.TypeFor[T]())
+			count := 0
+			err := xSession.StreamRecords(dbSession.WithContext(reqCtx), conditions, func(record T) error {
+				count++
+				return list.Write(record)
+			})
+			if err != nil {
+				log.Error("Export interrupted", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.Int("records", count), slog.String("error", err.Error()))
+				return err
+			}
+			log.Info("Export finished", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.Int("records", count))
+			return list.Close()
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+// HistoryEntry is one change in the audit trail of a record
+type HistoryEntry struct {
+	Operation string
+	Actor     string
+	RequestID string
+	Timestamp time.Time
+	Changes   xModels.FieldChanges
+}
+
+func PersonHistory(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return recordHistory(reqCtx, dbSession, log, "person", reqObjID)
+	}
+}
+
+func AnimalHistory(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return recordHistory(reqCtx, dbSession, log, "animal", reqObjID)
+	}
+}
+
+func recordHistory(reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) RespDetail {
+	if reqObjID == "" {
+		errResponse := fmt.Sprintf("%s ID not provided in the URL", resource)
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	entries, err := xSession.History(dbSession.WithContext(reqCtx), resource, reqObjID)
+	if err != nil {
+		log.Error("Error retrieving history", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving history: %s", err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+	if len(entries) == 0 {
+		errResponse := fmt.Sprintf("No history for %s with ID %s", resource, reqObjID)
+		return RespDetail{
+			Statuscode: http.StatusNotFound,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	history := make([]HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		changes := xModels.FieldChanges{}
+		if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+			log.Error("Corrupt audit entry", slog.String("request-id", RequestID(reqCtx)), slog.String("entry", entry.ID), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Audit entry %s cannot be read: %s", entry.ID, err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		history = append(history, HistoryEntry{
+			Operation: entry.Operation,
+			Actor:     entry.Actor,
+			RequestID: entry.RequestID,
+			Timestamp: entry.Timestamp,
+			Changes:   changes,
+		})
+	}
+
+	log.Info("History retrieved successfully", slog.String("request-id", RequestID(reqCtx)), slog.Int("entries", len(history)))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Body:       history,
+	}
+}
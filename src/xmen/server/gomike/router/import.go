@@ -0,0 +1,352 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+
+	xCodec "gomike/codec"
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+const (
+	importBatchSize = 500
+
+	ImportModeAtomic     = "atomic"
+	ImportModeBestEffort = "best-effort"
+
+	importInserted = "inserted"
+	importFailed   = "failed"
+	importSkipped  = "skipped"
+)
+
+// ImportReport is the answer to a bulk import, Rows holds one entry per record of the upload
+type ImportReport struct {
+	Mode     string
+	Total    int
+	Inserted int
+	Failed   int
+	Rows     []ImportRow
+}
+
+// ImportRow reports the outcome of one record, Line is where the record starts in the upload
+type ImportRow struct {
+	Line   int
+	ID     string `json:",omitempty"`
+	Status string
+	Error  string `json:",omitempty"`
+}
+
+var errImportAborted = errors.New("import aborted")
+
+func ImportPersons(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return importRecords(reqCtx, dbSession, log, "person", reqBody,
+			func() xModels.Person {
+				return xModels.Person{ID: uuid.New().String(), Kind: "person"}
+			},
+			func(person xModels.Person) string { return person.ID },
+		)
+	}
+}
+
+func ImportAnimals(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return importRecords(reqCtx, dbSession, log, "animal", reqBody,
+			func() xModels.Animal {
+				return xModels.Animal{ID: uuid.New().String()}
+			},
+			func(animal xModels.Animal) string { return animal.ID },
+		)
+	}
+}
+
+// importRecords streams an NDJSON or CSV upload into the database in batches. In atomic mode a single
+// bad record rolls back the whole upload, in best-effort mode every valid record is kept.
+func importRecords[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqBody io.Reader, newRecord func() T, idOf func(T) string) RespDetail {
+	mode := queryParams(reqCtx).Get("mode")
+	if mode == "" {
+		mode = ImportModeAtomic
+	}
+	if mode != ImportModeAtomic && mode != ImportModeBestEffort {
+		log.Error("Unknown import mode", slog.String("request-id", RequestID(reqCtx)), slog.String("mode", mode))
+		errResponse := fmt.Sprintf("Unknown import mode %q, expected %s or %s", mode, ImportModeAtomic, ImportModeBestEffort)
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	report := ImportReport{Mode: mode, Rows: []ImportRow{}}
+	batch := []T{}
+	pending := []int{}
+	aborted := false
+
+	fail := func(i int, err error) {
+		report.Rows[i].Status = importFailed
+		report.Rows[i].Error = err.Error()
+		report.Failed++
+	}
+
+	flush := func(db *xDb.DBSession) error {
+		defer func() {
+			batch = batch[:0]
+			pending = pending[:0]
+		}()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := xSession.CreateRecords(db, batch, importBatchSize)
+		if err == nil {
+			for _, i := range pending {
+				report.Rows[i].Status = importInserted
+			}
+			report.Inserted += len(pending)
+			return nil
+		}
+		if mode == ImportModeAtomic {
+			for _, i := range pending {
+				fail(i, err)
+			}
+			return err
+		}
+		// The batch went in as one statement, retry record by record to single out the ones the database refuses
+		for n, record := range batch {
+			i := pending[n]
+			if err := xSession.CreateRecord(db, record); err != nil {
+				fail(i, err)
+				continue
+			}
+			report.Rows[i].Status = importInserted
+			report.Inserted++
+		}
+		return nil
+	}
+
+	load := func(db *xDb.DBSession) error {
+		err := readImport(reqCtx, reqBody, newRecord, func(line int, record T, err error) error {
+			report.Rows = append(report.Rows, ImportRow{Line: line, Status: importSkipped})
+			i := len(report.Rows) - 1
+			if err == nil {
+				report.Rows[i].ID = idOf(record)
+				err = xSession.Validate(record)
+			}
+			if err != nil {
+				fail(i, err)
+				if mode == ImportModeAtomic && !aborted {
+					aborted = true
+					batch = batch[:0]
+					pending = pending[:0]
+				}
+				return nil
+			}
+			if aborted {
+				return nil
+			}
+			batch = append(batch, record)
+			pending = append(pending, i)
+			if len(batch) < importBatchSize {
+				return nil
+			}
+			if err := flush(db); err != nil {
+				aborted = true
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !aborted {
+			if err := flush(db); err != nil {
+				aborted = true
+			}
+		}
+		if aborted {
+			return errImportAborted
+		}
+		return nil
+	}
+
+	var err error
+	if mode == ImportModeAtomic {
+		err = xSession.Transaction(dbSession.WithContext(reqCtx), load)
+	} else {
+		err = load(dbSession.WithContext(reqCtx))
+	}
+	report.Total = len(report.Rows)
+
+	var parseErr *importParseError
+	switch {
+	case errors.As(err, &parseErr):
+		log.Error("Failed to read import body", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", parseErr.Error()))
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(parseErr.Error()),
+		}
+	case errors.Is(err, errImportAborted):
+		// Everything written before the failure was rolled back with the transaction
+		for i := range report.Rows {
+			if report.Rows[i].Status == importInserted {
+				report.Rows[i].Status = importSkipped
+			}
+		}
+		report.Inserted = 0
+		log.Error("Import rolled back", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.Int("failed", report.Failed))
+		return RespDetail{
+			Statuscode: http.StatusUnprocessableEntity,
+			Body:       report,
+		}
+	case err != nil:
+		log.Error("Import failed", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Failed to import %s records: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	if report.Total == 0 {
+		log.Error("Import body holds no records", slog.String("request-id", RequestID(reqCtx)))
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte("Request body holds no records"),
+		}
+	}
+
+	log.Info("Import finished", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.Int("inserted", report.Inserted), slog.Int("failed", report.Failed))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Body:       report,
+	}
+}
+
+// importParseError means the upload as a whole cannot be read, as opposed to a single bad record
+type importParseError struct {
+	err error
+}
+
+func (e *importParseError) Error() string {
+	return fmt.Sprintf("Failed to read import body: %s", e.err.Error())
+}
+
+// readImport decodes the upload one record at a time and hands each one, or the reason it could not
+// be decoded, to onRecord
+func readImport[T any](reqCtx context.Context, reqBody io.Reader, newRecord func() T, onRecord func(line int, record T, err error) error) error {
+	switch requestCodec(reqCtx).(type) {
+	case xCodec.NDJSON:
+		return readNDJSON(reqCtx, reqBody, newRecord, onRecord)
+	case xCodec.CSV:
+		return readCSV(reqCtx, reqBody, newRecord, onRecord)
+	}
+	return &importParseError{err: fmt.Errorf("imports accept %s or %s", xCodec.NDJSON{}.MediaType(), xCodec.CSV{}.MediaType())}
+}
+
+func readNDJSON[T any](reqCtx context.Context, reqBody io.Reader, newRecord func() T, onRecord func(line int, record T, err error) error) error {
+	reader := bufio.NewReader(reqBody)
+	for line := 1; ; line++ {
+		if err := reqCtx.Err(); err != nil {
+			return err
+		}
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return &importParseError{err: readErr}
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			record := newRecord()
+			restore := keepReadOnly(&record)
+			err := decodeStrict(data, &record)
+			restore()
+			if err := onRecord(line, record, err); err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+func readCSV[T any](reqCtx context.Context, reqBody io.Reader, newRecord func() T, onRecord func(line int, record T, err error) error) error {
+	reader := csv.NewReader(reqBody)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return &importParseError{err: err}
+	}
+	elemType := reflect.TypeOf(newRecord())
+	columns := xCodec.CSVHeader(reflect.ValueOf(newRecord()))
+	for _, name := range header {
+		if !slices.Contains(columns, name) {
+			return &importParseError{err: fmt.Errorf("unknown column %q", name)}
+		}
+	}
+
+	for {
+		if err := reqCtx.Err(); err != nil {
+			return err
+		}
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		record := newRecord()
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			err = onRecord(parseErr.StartLine, record, err)
+		case err != nil:
+			return &importParseError{err: err}
+		case len(fields) != len(header):
+			line, _ := reader.FieldPos(0)
+			err = onRecord(line, record, fmt.Errorf("expected %d fields, got %d", len(header), len(fields)))
+		default:
+			line, _ := reader.FieldPos(0)
+			err = onRecord(line, record, decodeCSVRecord(header, fields, elemType, &record))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decodeCSVRecord fills record from one CSV row, empty cells keep the defaults of the record
+func decodeCSVRecord(header, fields []string, elemType reflect.Type, record any) error {
+	row, err := xCodec.CSVRow(header, fields, elemType)
+	if err != nil {
+		return err
+	}
+	for name, value := range row {
+		if value == "" {
+			delete(row, name)
+		}
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	restore := keepReadOnly(record)
+	defer restore()
+	return decodeStrict(data, record)
+}
+
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+// ListPersonAnimals pages through the animals owned by a person, ?after= takes the ID of the last animal of the previous page
+func ListPersonAnimals(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		query := queryParams(reqCtx)
+		limit := defaultTrashPageSize
+		if raw := query.Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 || parsed > maxTrashPageSize {
+				errResponse := fmt.Sprintf("limit must be between 1 and %d", maxTrashPageSize)
+				return RespDetail{
+					Statuscode: http.StatusBadRequest,
+					Message:    []byte(errResponse),
+				}
+			}
+			limit = parsed
+		}
+
+		if _, err := xSession.ReadRecord[xModels.Person](dbSession.WithContext(reqCtx), reqObjID); err != nil {
+			return ownershipError(reqCtx, log, err, "Error retrieving person", reqObjID, "")
+		}
+
+		animals, err := xSession.OwnedAnimals(dbSession.WithContext(reqCtx), reqObjID, query.Get("after"), limit)
+		if err != nil {
+			return ownershipError(reqCtx, log, err, "Error listing animals", reqObjID, "")
+		}
+
+		log.Info("Owned animals listed successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID), slog.Int("animals", len(animals)))
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       animals,
+		}
+	}
+}
+
+// AdoptAnimal makes the person the owner of the animal, taking it from its previous owner if it had one
+func AdoptAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		animalID := pathValue(reqCtx, "animalID")
+		animal, err := xSession.SetOwner(dbSession.WithContext(reqCtx), animalID, reqObjID)
+		if err != nil {
+			return ownershipError(reqCtx, log, err, "Failed to adopt animal", reqObjID, animalID)
+		}
+
+		log.Info("Animal adopted", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID), slog.String("animalID", animalID))
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       animal,
+		}
+	}
+}
+
+// ReleaseAnimal leaves an animal owned by the person without an owner
+func ReleaseAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		animalID := pathValue(reqCtx, "animalID")
+		animal, err := xSession.ReadRecord[xModels.Animal](dbSession.WithContext(reqCtx), animalID)
+		if err != nil {
+			return ownershipError(reqCtx, log, fmt.Errorf("animal %s: %w", animalID, err), "Error retrieving animal", reqObjID, animalID)
+		}
+		if animal.OwnerID != reqObjID {
+			errResponse := fmt.Sprintf("Animal with ID %s is not owned by person %s", animalID, reqObjID)
+			return RespDetail{
+				Statuscode: http.StatusNotFound,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		animal, err = xSession.SetOwner(dbSession.WithContext(reqCtx), animalID, "")
+		if err != nil {
+			return ownershipError(reqCtx, log, err, "Failed to release animal", reqObjID, animalID)
+		}
+
+		log.Info("Animal released", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID), slog.String("animalID", animalID))
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       animal,
+		}
+	}
+}
+
+// ownershipError answers 404 when the person or the animal is missing and 500 otherwise
+func ownershipError(reqCtx context.Context, log *slog.Logger, err error, action string, personID string, animalID string) RespDetail {
+	if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+		errResponse := fmt.Sprintf("Person with ID %s not found", personID)
+		if animalID != "" && strings.Contains(err.Error(), "animal "+animalID) {
+			errResponse = fmt.Sprintf("Animal with ID %s not found", animalID)
+		}
+		return RespDetail{
+			Statuscode: http.StatusNotFound,
+			Message:    []byte(errResponse),
+		}
+	}
+	log.Error(action, slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+	errResponse := fmt.Sprintf("%s: %s", action, err.Error())
+	return RespDetail{
+		Statuscode: http.StatusInternalServerError,
+		Message:    []byte(errResponse),
+	}
+}
+
+// ownsAnimals reports whether err is a person deletion refused under the restrict rule
+func ownsAnimals(err error) bool {
+	return strings.Contains(err.Error(), xSession.ErrOwnerHasAnimals.Error())
+}
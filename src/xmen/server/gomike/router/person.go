@@ -2,7 +2,6 @@ package router
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -19,7 +18,7 @@ import (
 func GetPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Person ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Person ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Person ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -27,19 +26,23 @@ func GetPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 			}
 		}
 
-		log.Info("Got person ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got person ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
-		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, reqObjID)
+		if asOf := queryParams(reqCtx).Get("as_of"); asOf != "" {
+			return readAsOf[xModels.Person](reqCtx, dbSession, log, "person", reqObjID, asOf)
+		}
+
+		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Error("Person not found", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Error("Person not found", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Person  with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
 					Message:    []byte(errResponse),
 				}
 			}
-			log.Error("Error retrieving person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -47,20 +50,10 @@ func GetPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 			}
 		}
 
-		objDetails, err := json.Marshal(personPtr)
-		if err != nil {
-			log.Error("Failed to marshal person details", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
-			errResponse := fmt.Sprintf("Failed to marshal person details: %s", err.Error())
-			return RespDetail{
-				Statuscode: http.StatusInternalServerError,
-				Message:    []byte(errResponse),
-			}
-		}
-		log.Info("Person details retrieved successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Person details retrieved successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusOK,
-			Message:    objDetails,
-			Type:       "application/json",
+			Body:       personPtr,
 		}
 	}
 }
@@ -68,7 +61,7 @@ func GetPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context,
 func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Person ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Person ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Person ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -76,11 +69,11 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		log.Info("Got person ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got person ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -88,7 +81,7 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -96,10 +89,13 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			}
 		}
 
-		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, reqObjID)
+		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Info("Person not found, Creating a new one", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				if resp, ok := trashedConflict[xModels.Person](reqCtx, dbSession, log, "person", reqObjID); ok {
+					return resp
+				}
+				log.Info("Person not found, Creating a new one", slog.String("request-id", RequestID(reqCtx)))
 				// If the person is not found, create a new person with the provided ID
 				person := xModels.Person{
 					ID:            reqObjID,
@@ -107,33 +103,36 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 					Cloned:        false,
 					ClonedFromRef: "",
 				} // Create a new person instance with the ID from the URL
-				err = json.Unmarshal(body, &person)
+				err = decodeBody(reqCtx, body, &person)
 				if err != nil {
 					errResponse := fmt.Sprintf("Failed to unmarshal request body into person: %s", err.Error())
-					log.Error("Failed to unmarshal request body into person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+					log.Error("Failed to unmarshal request body into person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 					return RespDetail{
 						Statuscode: http.StatusBadRequest,
 						Message:    []byte(errResponse),
 					}
 
 				}
-				err = xSession.CreateRecord(dbSession, person)
+				err = xSession.CreateRecord(dbSession.WithContext(reqCtx), person)
 				if err != nil {
+					if resp, ok := cloneRefRefusal(err); ok {
+						return resp
+					}
 					errResponse := fmt.Sprintf("Failed to create person: %s", err.Error())
-					log.Error("Failed to create person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+					log.Error("Failed to create person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 					return RespDetail{
 						Statuscode: http.StatusInternalServerError,
 						Message:    []byte(errResponse),
 					}
 
 				}
-				log.Info("New person created successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Info("New person created successfully", slog.String("request-id", RequestID(reqCtx)))
 				return RespDetail{
 					Statuscode: http.StatusNoContent,
 				}
 
 			}
-			log.Error("Error retrieving person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving person with ID %s: %s", reqObjID, err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -142,10 +141,10 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		log.Info("Updating existing person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = json.Unmarshal(body, personPtr)
+		log.Info("Updating existing person", slog.String("request-id", RequestID(reqCtx)))
+		err = decodeBody(reqCtx, body, personPtr)
 		if err != nil {
-			log.Error("Failed to unmarshal request body into person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to unmarshal request body into person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -154,9 +153,12 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		err = xSession.UpdateRecord(dbSession, *personPtr)
+		err = xSession.UpdateRecord(dbSession.WithContext(reqCtx), *personPtr)
 		if err != nil {
-			log.Error("Failed to update person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to update person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to update person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -165,7 +167,7 @@ func UpdatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		log.Info("Person updated successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Person updated successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusNoContent,
 		}
@@ -176,7 +178,7 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -186,7 +188,7 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 		}
 
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -202,10 +204,10 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 			ClonedFromRef: "",
 		} // Create a new person instance
 
-		log.Info("Creating a new person with ID", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", person.ID))
-		err = json.Unmarshal(body, &person)
+		log.Info("Creating a new person with ID", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", person.ID))
+		err = decodeBody(reqCtx, body, &person)
 		if err != nil {
-			log.Error("Failed to unmarshal request body into person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to unmarshal request body into person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -214,9 +216,12 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		err = xSession.CreateRecord(dbSession, person)
+		err = xSession.CreateRecord(dbSession.WithContext(reqCtx), person)
 		if err != nil {
-			log.Error("Failed to create person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to create person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to create person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -226,7 +231,7 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 		}
 
 		res := fmt.Sprintf("Person with ID %s added", person.ID)
-		log.Info("New Person created successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("New Person created successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusCreated,
 			Message:    []byte(res),
@@ -238,7 +243,7 @@ func CreatePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Person ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Person ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Person ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -247,11 +252,11 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 
 		}
 
-		log.Info("Got person ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got person ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
 
 		body, err := io.ReadAll(reqBody)
 		if err != nil {
-			log.Error("Failed to read request body", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to read request body", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -261,7 +266,7 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 		}
 
 		if len(body) == 0 {
-			log.Error("Request body is empty", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Request body is empty", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Request body is empty"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -270,10 +275,10 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 
 		}
 
-		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, reqObjID)
+		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Warn("Patch method called on non-existing person, only allowed on existing records", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Warn("Patch method called on non-existing person, only allowed on existing records", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Patch method is only allowed on existing records. Person with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
@@ -281,7 +286,7 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 				}
 
 			}
-			log.Error("Error retrieving person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving person with ID %s: %s", reqObjID, err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -290,9 +295,9 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 
 		}
 
-		err = json.Unmarshal(body, personPtr)
+		err = decodeBody(reqCtx, body, personPtr)
 		if err != nil {
-			log.Error("Failed to unmarshal request body into person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Failed to unmarshal request body into person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to unmarshal request body into person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -301,10 +306,13 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 
 		}
 
-		log.Info("Patching existing person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = xSession.UpdateRecord(dbSession, *personPtr)
+		log.Info("Patching existing person", slog.String("request-id", RequestID(reqCtx)))
+		err = xSession.UpdateRecord(dbSession.WithContext(reqCtx), *personPtr)
 		if err != nil {
-			log.Error("Failed to update person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			log.Error("Failed to update person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to update person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -313,7 +321,7 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 
 		}
 
-		log.Info("Person patched successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Person patched successfully", slog.String("request-id", RequestID(reqCtx)))
 		return RespDetail{
 			Statuscode: http.StatusNoContent,
 		}
@@ -324,7 +332,7 @@ func PatchPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Contex
 func DeletePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
 	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
 		if reqObjID == "" {
-			log.Error("Person ID not provided in the URL", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+			log.Error("Person ID not provided in the URL", slog.String("request-id", RequestID(reqCtx)))
 			errResponse := "Person ID not provided in the URL"
 			return RespDetail{
 				Statuscode: http.StatusBadRequest,
@@ -333,12 +341,16 @@ func DeletePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		log.Info("Got person ID from request", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("reqObjID", reqObjID))
+		log.Info("Got person ID from request", slog.String("request-id", RequestID(reqCtx)), slog.String("reqObjID", reqObjID))
+
+		if queryParams(reqCtx).Get("force") == "true" {
+			return forceDelete[xModels.Person](reqCtx, dbSession, log, "person", reqObjID)
+		}
 
-		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession, reqObjID)
+		personPtr, err := xSession.ReadRecord[xModels.Person](dbSession.WithContext(reqCtx), reqObjID)
 		if err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
-				log.Error("Person not found", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+				log.Error("Person not found", slog.String("request-id", RequestID(reqCtx)))
 				errResponse := fmt.Sprintf("Person with ID %s not found", reqObjID)
 				return RespDetail{
 					Statuscode: http.StatusNotFound,
@@ -346,7 +358,7 @@ func DeletePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 				}
 
 			}
-			log.Error("Error retrieving person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			log.Error("Error retrieving person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Error retrieving person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -355,10 +367,20 @@ func DeletePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		log.Info("Deleting person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
-		err = xSession.DeleteRecord(dbSession, *personPtr)
+		log.Info("Deleting person", slog.String("request-id", RequestID(reqCtx)))
+		err = xSession.DeleteRecord(dbSession.WithContext(reqCtx), *personPtr)
 		if err != nil {
-			log.Error("Failed to delete person", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)), slog.String("error", err.Error()))
+			if resp, ok := cloneRefRefusal(err); ok {
+				return resp
+			}
+			if ownsAnimals(err) {
+				errResponse := fmt.Sprintf("Person with ID %s still owns animals, release them first", reqObjID)
+				return RespDetail{
+					Statuscode: http.StatusConflict,
+					Message:    []byte(errResponse),
+				}
+			}
+			log.Error("Failed to delete person", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
 			errResponse := fmt.Sprintf("Failed to delete person: %s", err.Error())
 			return RespDetail{
 				Statuscode: http.StatusInternalServerError,
@@ -367,7 +389,7 @@ func DeletePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Conte
 
 		}
 
-		log.Info("Person deleted successfully", slog.String("request-id", reqCtx.Value(RequestIDKey("requestID")).(string)))
+		log.Info("Person deleted successfully", slog.String("request-id", RequestID(reqCtx)))
 		res := fmt.Sprintf("Person with ID %s deleted", reqObjID)
 		return RespDetail{
 			Statuscode: http.StatusOK,
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	defaultTrashPageSize = 100
+	maxTrashPageSize     = 1000
+)
+
+func ListPersonTrash(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return listTrash[xModels.Person](reqCtx, dbSession, log, "person")
+	}
+}
+
+func ListAnimalTrash(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return listTrash[xModels.Animal](reqCtx, dbSession, log, "animal")
+	}
+}
+
+func RestorePerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return restoreRecord[xModels.Person](reqCtx, dbSession, log, "person", reqObjID)
+	}
+}
+
+func RestoreAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return restoreRecord[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID)
+	}
+}
+
+// listTrash pages through the soft-deleted records, ?after= takes the ID of the last record of the previous page
+func listTrash[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string) RespDetail {
+	query := queryParams(reqCtx)
+	limit := defaultTrashPageSize
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxTrashPageSize {
+			errResponse := fmt.Sprintf("limit must be between 1 and %d", maxTrashPageSize)
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(errResponse),
+			}
+		}
+		limit = parsed
+	}
+
+	records, err := xSession.ListDeletedRecords[T](dbSession.WithContext(reqCtx), query.Get("after"), limit)
+	if err != nil {
+		log.Error("Error listing trash", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error listing deleted %s records: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	log.Info("Trash listed successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.Int("records", len(records)))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Body:       records,
+	}
+}
+
+func restoreRecord[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) RespDetail {
+	if reqObjID == "" {
+		errResponse := fmt.Sprintf("%s ID not provided in the URL", resource)
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	record, err := xSession.RestoreRecord[T](dbSession.WithContext(reqCtx), reqObjID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("No %s with ID %s in the trash", resource, reqObjID)
+			return RespDetail{
+				Statuscode: http.StatusNotFound,
+				Message:    []byte(errResponse),
+			}
+		}
+		log.Error("Failed to restore record", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Failed to restore %s: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	log.Info("Record restored successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("reqObjID", reqObjID))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Body:       record,
+	}
+}
+
+// forceDelete hard-deletes a record, live or in the trash, on behalf of an administrator
+func forceDelete[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) RespDetail {
+	if resp, ok := adminOnly(reqCtx, log); !ok {
+		return resp
+	}
+
+	err := xSession.PurgeRecord[T](dbSession.WithContext(reqCtx), reqObjID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("%s with ID %s not found", resource, reqObjID)
+			return RespDetail{
+				Statuscode: http.StatusNotFound,
+				Message:    []byte(errResponse),
+			}
+		}
+		if resp, ok := cloneRefRefusal(err); ok {
+			return resp
+		}
+		if ownsAnimals(err) {
+			errResponse := fmt.Sprintf("%s with ID %s still owns animals, release them first", resource, reqObjID)
+			return RespDetail{
+				Statuscode: http.StatusConflict,
+				Message:    []byte(errResponse),
+			}
+		}
+		log.Error("Failed to purge record", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Failed to delete %s: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	log.Info("Record deleted permanently", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("reqObjID", reqObjID))
+	res := fmt.Sprintf("%s with ID %s permanently deleted", resource, reqObjID)
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Message:    []byte(res),
+	}
+}
+
+// trashedConflict answers 409 when a write would create a record whose ID is taken by one in the trash
+func trashedConflict[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) (RespDetail, bool) {
+	trashed, err := xSession.InTrash[T](dbSession.WithContext(reqCtx), reqObjID)
+	if err != nil {
+		log.Error("Error checking the trash", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving %s: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}, true
+	}
+	if !trashed {
+		return RespDetail{}, false
+	}
+	errResponse := fmt.Sprintf("%s with ID %s is in the trash, bring it back with POST /%s/%s/restore", resource, reqObjID, resource, reqObjID)
+	return RespDetail{
+		Statuscode: http.StatusConflict,
+		Message:    []byte(errResponse),
+	}, true
+}
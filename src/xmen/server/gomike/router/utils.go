@@ -1,9 +1,213 @@
 package router
 
-type RequestIDKey string
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
 
+	xCodec "gomike/codec"
+	xSession "gomike/session"
+)
+
+// RequestIDHeader carries the correlation ID between clients, proxies and gomike
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+type principalKey struct{}
+
+type adminKey struct{}
+
+type codecKey struct{}
+
+type queryKey struct{}
+
+type pathKey struct{}
+
+type responseCodecKey struct{}
+
+// RespDetail is what a router function answers with. Body, when set, is a typed value
+// encoded with the codec negotiated from the Accept header; otherwise Message is sent as is,
+// or as a problem document for error statuses. Stream, when set, writes a body too large to
+// hold in memory straight to the client.
 type RespDetail struct {
 	Statuscode int
 	Message    []byte `default:""`
 	Type       string `default:"text/plain"`
+	Body       any
+	Stream     func(io.Writer) error
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the authenticated principal carried by ctx, or an empty string if there is none
+func Principal(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// WithAdmin returns a copy of ctx marking the principal as an administrator
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether the principal carried by ctx is an administrator
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// WithRequestCodec returns a copy of ctx carrying the codec matching the request Content-Type
+func WithRequestCodec(ctx context.Context, c xCodec.Codec) context.Context {
+	return context.WithValue(ctx, codecKey{}, c)
+}
+
+// WithQuery returns a copy of ctx carrying the query parameters of the request
+func WithQuery(ctx context.Context, query url.Values) context.Context {
+	return context.WithValue(ctx, queryKey{}, query)
+}
+
+// WithPathValues returns a copy of ctx carrying the wildcards matched in the path of req
+func WithPathValues(ctx context.Context, req *http.Request) context.Context {
+	return context.WithValue(ctx, pathKey{}, req.PathValue)
+}
+
+// pathValue returns the path wildcard called name carried by ctx, empty when there is none
+func pathValue(ctx context.Context, name string) string {
+	value, ok := ctx.Value(pathKey{}).(func(string) string)
+	if !ok {
+		return ""
+	}
+	return value(name)
+}
+
+// queryParams returns the query parameters carried by ctx, empty when there are none
+func queryParams(ctx context.Context) url.Values {
+	query, ok := ctx.Value(queryKey{}).(url.Values)
+	if !ok {
+		return url.Values{}
+	}
+	return query
+}
+
+// requestCodec returns the codec negotiated for the request body, JSON by default
+func requestCodec(ctx context.Context) xCodec.Codec {
+	c, ok := ctx.Value(codecKey{}).(xCodec.Codec)
+	if !ok {
+		return xCodec.JSON{}
+	}
+	return c
+}
+
+// WithResponseCodec returns a copy of ctx carrying the codec negotiated from the Accept header
+func WithResponseCodec(ctx context.Context, c xCodec.Codec) context.Context {
+	return context.WithValue(ctx, responseCodecKey{}, c)
+}
+
+// responseCodec returns the codec negotiated for the response, JSON by default
+func responseCodec(ctx context.Context) xCodec.Codec {
+	c, ok := ctx.Value(responseCodecKey{}).(xCodec.Codec)
+	if !ok {
+		return xCodec.JSON{}
+	}
+	return c
+}
+
+// decodeBody decodes a request body with the codec negotiated for the request, JSON by default.
+// Fields tagged readonly:"true" keep the value they had, clients cannot set them.
+func decodeBody(ctx context.Context, body []byte, v any) error {
+	restore := keepReadOnly(v)
+	defer restore()
+	return requestCodec(ctx).Unmarshal(body, v)
+}
+
+// keepReadOnly saves the readonly fields of the struct v points to and returns a function putting them back
+func keepReadOnly(v any) func() {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return func() {}
+	}
+	saved := map[string]reflect.Value{}
+	var collect func(value reflect.Value, prefix string)
+	collect = func(value reflect.Value, prefix string) {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			switch {
+			case !field.IsExported():
+			case field.Anonymous && field.Type.Kind() == reflect.Struct:
+				collect(value.Field(i), prefix+field.Name+".")
+			case field.Tag.Get("readonly") == "true":
+				copied := reflect.New(field.Type).Elem()
+				copied.Set(value.Field(i))
+				saved[prefix+field.Name] = copied
+			}
+		}
+	}
+	collect(target.Elem(), "")
+	return func() {
+		for path, value := range saved {
+			field := target.Elem()
+			for _, name := range strings.Split(path, ".") {
+				field = field.FieldByName(name)
+			}
+			field.Set(value)
+		}
+	}
+}
+
+// ValidRequestID reports whether an incoming request ID is safe to adopt and echo back
+func ValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > 128 {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// adminOnly refuses requests from principals that are not administrators
+func adminOnly(reqCtx context.Context, log *slog.Logger) (RespDetail, bool) {
+	if IsAdmin(reqCtx) {
+		return RespDetail{}, true
+	}
+	log.Warn("Administrator route refused", slog.String("request-id", RequestID(reqCtx)), slog.String("principal", Principal(reqCtx)))
+	return RespDetail{
+		Statuscode: http.StatusForbidden,
+		Message:    []byte("Only administrators can do this"),
+	}, false
+}
+
+// cloneRefRefusal answers 400 for a ClonedFromRef refused on write and 409 for an origin that still has clones
+func cloneRefRefusal(err error) (RespDetail, bool) {
+	switch {
+	case strings.Contains(err.Error(), xSession.ErrInvalidCloneRef.Error()):
+		return RespDetail{Statuscode: http.StatusBadRequest, Message: []byte(err.Error())}, true
+	case strings.Contains(err.Error(), xSession.ErrOriginHasClones.Error()):
+		return RespDetail{Statuscode: http.StatusConflict, Message: []byte(err.Error())}, true
+	}
+	return RespDetail{}, false
 }
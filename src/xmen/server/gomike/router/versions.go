@@ -0,0 +1,177 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	xCodec "gomike/codec"
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+func PersonVersion(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return recordVersion[xModels.Person](reqCtx, dbSession, log, "person", reqObjID)
+	}
+}
+
+func AnimalVersion(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return recordVersion[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID)
+	}
+}
+
+// RevertPerson writes version n of a person back through UpdatePerson
+func RevertPerson(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	update := UpdatePerson(dbSession, log)
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return revertRecord[xModels.Person](reqCtx, dbSession, log, "person", reqObjID, update)
+	}
+}
+
+// RevertAnimal writes version n of an animal back through UpdateAnimal
+func RevertAnimal(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	update := UpdateAnimal(dbSession, log)
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		return revertRecord[xModels.Animal](reqCtx, dbSession, log, "animal", reqObjID, update)
+	}
+}
+
+// readAsOf answers a read carrying ?as_of= with the version of the record that was current at that time
+func readAsOf[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string, rawAsOf string) RespDetail {
+	asOf, err := time.Parse(time.RFC3339Nano, rawAsOf)
+	if err != nil {
+		errResponse := fmt.Sprintf("as_of must be an RFC 3339 timestamp: %s", err.Error())
+		return RespDetail{
+			Statuscode: http.StatusBadRequest,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	version, err := xSession.VersionAsOf(dbSession.WithContext(reqCtx), resource, reqObjID, asOf)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("%s with ID %s did not exist at %s", resource, reqObjID, rawAsOf)
+			return RespDetail{
+				Statuscode: http.StatusNotFound,
+				Message:    []byte(errResponse),
+			}
+		}
+		log.Error("Error retrieving version", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving %s: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+	if version.Data == "" {
+		errResponse := fmt.Sprintf("%s with ID %s was deleted at %s", resource, reqObjID, rawAsOf)
+		return RespDetail{
+			Statuscode: http.StatusNotFound,
+			Message:    []byte(errResponse),
+		}
+	}
+	return versionResponse[T](reqCtx, log, version)
+}
+
+func recordVersion[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) RespDetail {
+	version, resp, ok := lookupVersion(reqCtx, dbSession, log, resource, reqObjID)
+	if !ok {
+		return resp
+	}
+	if version.Data == "" {
+		errResponse := fmt.Sprintf("Version %d of %s with ID %s records its deletion", version.Version, resource, reqObjID)
+		return RespDetail{
+			Statuscode: http.StatusNotFound,
+			Message:    []byte(errResponse),
+		}
+	}
+	return versionResponse[T](reqCtx, log, version)
+}
+
+// revertRecord copies version n over the live record as a new write, so the revert is versioned and audited like any update
+func revertRecord[T any](reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string, update func(context.Context, string, io.ReadCloser) RespDetail) RespDetail {
+	version, resp, ok := lookupVersion(reqCtx, dbSession, log, resource, reqObjID)
+	if !ok {
+		return resp
+	}
+	if version.Data == "" {
+		errResponse := fmt.Sprintf("Version %d of %s with ID %s records its deletion and cannot be reverted to", version.Version, resource, reqObjID)
+		return RespDetail{
+			Statuscode: http.StatusConflict,
+			Message:    []byte(errResponse),
+		}
+	}
+	_, err := xSession.ReadRecord[T](dbSession.WithContext(reqCtx), reqObjID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("%s with ID %s not found, restore it from the trash before reverting it", resource, reqObjID)
+			return RespDetail{
+				Statuscode: http.StatusNotFound,
+				Message:    []byte(errResponse),
+			}
+		}
+		log.Error("Error retrieving record", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving %s: %s", resource, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+
+	log.Info("Reverting record", slog.String("request-id", RequestID(reqCtx)), slog.String("resource", resource), slog.String("reqObjID", reqObjID), slog.Int64("version", version.Version))
+	// Versions are stored as JSON whatever the client sends
+	updateCtx := WithRequestCodec(reqCtx, xCodec.JSON{})
+	return update(updateCtx, reqObjID, io.NopCloser(bytes.NewReader([]byte(version.Data))))
+}
+
+// lookupVersion reads the version named by the {version} path wildcard, answering with resp when it cannot
+func lookupVersion(reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, resource string, reqObjID string) (*xModels.RecordVersion, RespDetail, bool) {
+	if reqObjID == "" {
+		errResponse := fmt.Sprintf("%s ID not provided in the URL", resource)
+		return nil, RespDetail{Statuscode: http.StatusBadRequest, Message: []byte(errResponse)}, false
+	}
+	n, err := strconv.ParseInt(pathValue(reqCtx, "version"), 10, 64)
+	if err != nil || n <= 0 {
+		errResponse := "Version must be a positive integer"
+		return nil, RespDetail{Statuscode: http.StatusBadRequest, Message: []byte(errResponse)}, false
+	}
+
+	version, err := xSession.Version(dbSession.WithContext(reqCtx), resource, reqObjID, n)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("No version %d of %s with ID %s", n, resource, reqObjID)
+			return nil, RespDetail{Statuscode: http.StatusNotFound, Message: []byte(errResponse)}, false
+		}
+		log.Error("Error retrieving version", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving version %d of %s: %s", n, resource, err.Error())
+		return nil, RespDetail{Statuscode: http.StatusInternalServerError, Message: []byte(errResponse)}, false
+	}
+	return version, RespDetail{}, true
+}
+
+func versionResponse[T any](reqCtx context.Context, log *slog.Logger, version *xModels.RecordVersion) RespDetail {
+	record := new(T)
+	if err := json.Unmarshal([]byte(version.Data), record); err != nil {
+		log.Error("Corrupt record version", slog.String("request-id", RequestID(reqCtx)), slog.String("version", version.ID), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Version %d cannot be read: %s", version.Version, err.Error())
+		return RespDetail{
+			Statuscode: http.StatusInternalServerError,
+			Message:    []byte(errResponse),
+		}
+	}
+	log.Info("Record version retrieved successfully", slog.String("request-id", RequestID(reqCtx)), slog.Int64("version", version.Version))
+	return RespDetail{
+		Statuscode: http.StatusOK,
+		Body:       record,
+	}
+}
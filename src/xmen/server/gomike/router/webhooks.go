@@ -0,0 +1,357 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDeliveryPageSize = 100
+	maxDeliveryPageSize     = 1000
+	// maxWebhooks bounds the webhook listing, subscriptions are few
+	maxWebhooks = 1000
+)
+
+func CreateWebhook(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		body, err := io.ReadAll(reqBody)
+		if err != nil {
+			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		if len(body) == 0 {
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte("Request body is empty"),
+			}
+		}
+
+		hook := xModels.Webhook{ID: uuid.New().String()}
+		if err := decodeBody(reqCtx, body, &hook); err != nil {
+			errResponse := fmt.Sprintf("Failed to unmarshal request body into webhook: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(errResponse),
+			}
+		}
+		// A webhook starts delivering as soon as it is created
+		hook.Active = true
+		if hook.Secret == "" {
+			secret := make([]byte, 32)
+			rand.Read(secret)
+			hook.Secret = hex.EncodeToString(secret)
+		}
+		if err := validateWebhook(hook); err != nil {
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(err.Error()),
+			}
+		}
+
+		if err := xSession.CreateRecord(dbSession.WithContext(reqCtx), hook); err != nil {
+			log.Error("Failed to create webhook", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to create webhook: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		log.Info("Webhook created successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("webhook", hook.ID))
+		// The secret is answered this once so the receiver can check signatures
+		return RespDetail{
+			Statuscode: http.StatusCreated,
+			Body:       hook,
+		}
+	}
+}
+
+func ListWebhooks(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		hooks, err := xSession.ListRecords[xModels.Webhook](dbSession.WithContext(reqCtx), nil, "", maxWebhooks)
+		if err != nil {
+			log.Error("Error listing webhooks", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Error listing webhooks: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       hooks,
+		}
+	}
+}
+
+func GetWebhook(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		hook, resp, ok := readWebhook(reqCtx, dbSession, log, reqObjID)
+		if !ok {
+			return resp
+		}
+		hook.Secret = ""
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       hook,
+		}
+	}
+}
+
+// PatchWebhook changes the target, filters or Active flag of a webhook, setting Active to false pauses it
+func PatchWebhook(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		body, err := io.ReadAll(reqBody)
+		if err != nil {
+			errResponse := fmt.Sprintf("Failed to read request body: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		hook, resp, ok := readWebhook(reqCtx, dbSession, log, reqObjID)
+		if !ok {
+			return resp
+		}
+		wasActive := hook.Active
+		if err := decodeBody(reqCtx, body, hook); err != nil {
+			errResponse := fmt.Sprintf("Failed to unmarshal request body into webhook: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(errResponse),
+			}
+		}
+		if err := validateWebhook(*hook); err != nil {
+			return RespDetail{
+				Statuscode: http.StatusBadRequest,
+				Message:    []byte(err.Error()),
+			}
+		}
+
+		err = dbSession.WithContext(reqCtx).Transaction(func(tx *xDb.DBSession) error {
+			err := xSession.UpdateColumns(tx, *hook, "url", "resources", "kinds", "secret", "active", "updated_at", "updated_by")
+			if err != nil || wasActive || !hook.Active {
+				return err
+			}
+			// The sender pushed the deliveries back while the webhook was paused, resuming it sends them right away
+			return xSession.RequeuePendingDeliveries(tx, hook.ID, time.Now().UTC())
+		})
+		if err != nil {
+			log.Error("Failed to update webhook", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to update webhook: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		log.Info("Webhook updated successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("webhook", hook.ID))
+		hook.Secret = ""
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       hook,
+		}
+	}
+}
+
+func DeleteWebhook(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		hook, resp, ok := readWebhook(reqCtx, dbSession, log, reqObjID)
+		if !ok {
+			return resp
+		}
+		// Pending deliveries of a deleted webhook are dead-lettered by the sender
+		if err := xSession.DeleteRecord(dbSession.WithContext(reqCtx), *hook); err != nil {
+			log.Error("Failed to delete webhook", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to delete webhook: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		log.Info("Webhook deleted successfully", slog.String("request-id", RequestID(reqCtx)), slog.String("webhook", hook.ID))
+		res := fmt.Sprintf("Webhook with ID %s deleted", hook.ID)
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Message:    []byte(res),
+		}
+	}
+}
+
+// WebhookDeliveries pages through the delivery log of a webhook in event order, ?after= takes the EventSeq
+// of the last delivery of the previous page
+func WebhookDeliveries(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		query := queryParams(reqCtx)
+		var after int64
+		if raw := query.Get("after"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return RespDetail{
+					Statuscode: http.StatusBadRequest,
+					Message:    []byte("after must be the EventSeq of a delivery"),
+				}
+			}
+			after = parsed
+		}
+		limit := defaultDeliveryPageSize
+		if raw := query.Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 || parsed > maxDeliveryPageSize {
+				errResponse := fmt.Sprintf("limit must be between 1 and %d", maxDeliveryPageSize)
+				return RespDetail{
+					Statuscode: http.StatusBadRequest,
+					Message:    []byte(errResponse),
+				}
+			}
+			limit = parsed
+		}
+		if _, resp, ok := readWebhook(reqCtx, dbSession, log, reqObjID); !ok {
+			return resp
+		}
+
+		deliveries, err := xSession.Deliveries(dbSession.WithContext(reqCtx), reqObjID, after, limit)
+		if err != nil {
+			log.Error("Error listing webhook deliveries", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Error listing webhook deliveries: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+		return RespDetail{
+			Statuscode: http.StatusOK,
+			Body:       deliveries,
+		}
+	}
+}
+
+// RedeliverWebhook queues a delivery, dead-lettered or not, to be sent again with a fresh set of attempts
+func RedeliverWebhook(dbSession *xDb.DBSession, log *slog.Logger) func(context.Context, string, io.ReadCloser) RespDetail {
+	return func(reqCtx context.Context, reqObjID string, reqBody io.ReadCloser) RespDetail {
+		if resp, ok := adminOnly(reqCtx, log); !ok {
+			return resp
+		}
+		deliveryID := pathValue(reqCtx, "delivery")
+		notFound := RespDetail{
+			Statuscode: http.StatusNotFound,
+			Message:    []byte(fmt.Sprintf("Webhook %s has no delivery with ID %s", reqObjID, deliveryID)),
+		}
+		delivery, err := xSession.ReadRecord[xModels.WebhookDelivery](dbSession.WithContext(reqCtx), deliveryID)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+				return notFound
+			}
+			log.Error("Error retrieving webhook delivery", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Error retrieving webhook delivery: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		if delivery.WebhookID != reqObjID {
+			return notFound
+		}
+
+		delivery.Requeue(time.Now().UTC())
+		err = xSession.UpdateColumns(dbSession.WithContext(reqCtx), *delivery, "status", "attempts", "next_attempt_at")
+		if err != nil {
+			log.Error("Failed to queue webhook delivery", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+			errResponse := fmt.Sprintf("Failed to queue webhook delivery: %s", err.Error())
+			return RespDetail{
+				Statuscode: http.StatusInternalServerError,
+				Message:    []byte(errResponse),
+			}
+		}
+
+		log.Info("Webhook delivery queued again", slog.String("request-id", RequestID(reqCtx)), slog.String("delivery", delivery.ID))
+		return RespDetail{
+			Statuscode: http.StatusAccepted,
+			Body:       delivery,
+		}
+	}
+}
+
+func readWebhook(reqCtx context.Context, dbSession *xDb.DBSession, log *slog.Logger, reqObjID string) (*xModels.Webhook, RespDetail, bool) {
+	hook, err := xSession.ReadRecord[xModels.Webhook](dbSession.WithContext(reqCtx), reqObjID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			errResponse := fmt.Sprintf("Webhook with ID %s not found", reqObjID)
+			return nil, RespDetail{Statuscode: http.StatusNotFound, Message: []byte(errResponse)}, false
+		}
+		log.Error("Error retrieving webhook", slog.String("request-id", RequestID(reqCtx)), slog.String("error", err.Error()))
+		errResponse := fmt.Sprintf("Error retrieving webhook: %s", err.Error())
+		return nil, RespDetail{Statuscode: http.StatusInternalServerError, Message: []byte(errResponse)}, false
+	}
+	return hook, RespDetail{}, true
+}
+
+func validateWebhook(hook xModels.Webhook) error {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+	for _, resource := range splitList(hook.Resources) {
+		if resource != "person" && resource != "animal" {
+			return fmt.Errorf("unknown resource %q in Resources, expected person or animal", resource)
+		}
+	}
+	for _, kind := range splitList(hook.Kinds) {
+		if !slices.Contains(EventKinds, kind) {
+			return fmt.Errorf("unknown kind %q in Kinds, expected one of %s", kind, strings.Join(EventKinds, ", "))
+		}
+	}
+	return xSession.Validate(hook)
+}
+
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+
+	xChain "gomike/chain"
+	xCodec "gomike/codec"
+	xGql "gomike/gql"
+	xMetrics "gomike/metrics"
+	xModels "gomike/models"
+	xOpenAPI "gomike/openapi"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	apiTitle   = "gomike"
+	apiVersion = "1.0.0"
+)
+
+// registerRoutes wires every gomike route onto mux, it needs no live database so commands can inspect the routes
+func registerRoutes(mux *http.ServeMux, dbSession *xDb.DBSession, logger *slog.Logger, accessLog accessLogConfig, idempotency idempotencyConfig) *xChain.Chain {
+	admins := adminPrincipalsFromEnv()
+	api := xChain.New(mux).
+		Use("request-id", handleRequest).
+		Use("recovery", func(next http.Handler) http.Handler { return handleWithRecovery(logger, next) }).
+		Use("tracing", handleWithTracing).
+		Use("metrics", handleWithMetrics).
+		Use("access-log", func(next http.Handler) http.Handler { return handleWithAccessLog(accessLog, next) }).
+		Use("logger", func(next http.Handler) http.Handler { return handleWithLogger(logger, next) }).
+		Use("auth", func(next http.Handler) http.Handler { return handleWithAuth(authMiddleware(logger), admins, next) }).
+		Use("idempotency", func(next http.Handler) http.Handler { return handleWithIdempotency(logger, idempotency, next) })
+
+	personDocs := docsFor("person", xModels.Person{})
+	api.Handle("GET /person/{reqObjID}", handleWithRouter(logger, readRouteTimeout, xRouter.GetPerson(dbSession, logger)), xChain.WithMeta(personDocs.get))
+	api.Handle("PUT /person/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.UpdatePerson(dbSession, logger)), xChain.WithMeta(personDocs.put))
+	api.Handle("POST /person/", handleWithRouter(logger, defaultRouteTimeout, xRouter.CreatePerson(dbSession, logger)), xChain.WithMeta(personDocs.create))
+	api.Handle("PATCH /person/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.PatchPerson(dbSession, logger)), xChain.WithMeta(personDocs.patch))
+	api.Handle("DELETE /person/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.DeletePerson(dbSession, logger)), xChain.WithMeta(personDocs.delete))
+	api.Handle("GET /person/{reqObjID}/history", handleWithRouter(logger, readRouteTimeout, xRouter.PersonHistory(dbSession, logger)), xChain.WithMeta(personDocs.history))
+	api.Handle("GET /person/{reqObjID}/versions/{version}", handleWithRouter(logger, readRouteTimeout, xRouter.PersonVersion(dbSession, logger)), xChain.WithMeta(personDocs.version))
+	api.Handle("POST /person/{reqObjID}/revert/{version}", handleWithRouter(logger, defaultRouteTimeout, xRouter.RevertPerson(dbSession, logger)), xChain.WithMeta(personDocs.revert))
+	api.Handle("GET /person/{reqObjID}/animals", handleWithRouter(logger, readRouteTimeout, xRouter.ListPersonAnimals(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "list_person_animals",
+		Summary:     "List the animals owned by a person",
+		Tags:        []string{"person"},
+		Query: []xOpenAPI.Param{
+			{Name: "after", Description: "ID of the last animal of the previous page"},
+			{Name: "limit", Description: "Page size, 100 by default and at most 1000"},
+		},
+		Responses: []xOpenAPI.Response{{Status: http.StatusOK, Body: []xModels.Animal{}}},
+		Errors:    []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}))
+	api.Handle("POST /person/{reqObjID}/animals/{animalID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.AdoptAnimal(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "adopt_animal",
+		Summary:     "Make a person the owner of an animal, taking it from its previous owner",
+		Tags:        []string{"person"},
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: xModels.Animal{}}},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}))
+	api.Handle("DELETE /person/{reqObjID}/animals/{animalID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.ReleaseAnimal(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "release_animal",
+		Summary:     "Leave an animal owned by a person without an owner",
+		Tags:        []string{"person"},
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: xModels.Animal{}}},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}))
+	api.Handle("GET /person/:export", handleWithStream(logger, "person", xRouter.ExportPersons(dbSession, logger)), xChain.WithMeta(personDocs.export))
+	api.Handle("GET /person/:trash", handleWithRouter(logger, readRouteTimeout, xRouter.ListPersonTrash(dbSession, logger)), xChain.WithMeta(personDocs.trash))
+	api.Handle("POST /person/{reqObjID}/restore", handleWithRouter(logger, defaultRouteTimeout, xRouter.RestorePerson(dbSession, logger)), xChain.WithMeta(personDocs.restore))
+	api.Handle("POST /person/:import", handleWithRouter(logger, bulkRouteTimeout, xRouter.ImportPersons(dbSession, logger)), xChain.WithMeta(personDocs.importing), xChain.Without("idempotency"))
+
+	animalDocs := docsFor("animal", xModels.Animal{})
+	api.Handle("GET /animal/{reqObjID}", handleWithRouter(logger, readRouteTimeout, xRouter.GetAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.get))
+	api.Handle("PUT /animal/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.UpdateAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.put))
+	api.Handle("POST /animal/", handleWithRouter(logger, defaultRouteTimeout, xRouter.CreateAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.create))
+	api.Handle("PATCH /animal/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.PatchAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.patch))
+	api.Handle("DELETE /animal/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.DeleteAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.delete))
+	api.Handle("GET /animal/{reqObjID}/history", handleWithRouter(logger, readRouteTimeout, xRouter.AnimalHistory(dbSession, logger)), xChain.WithMeta(animalDocs.history))
+	api.Handle("GET /animal/{reqObjID}/versions/{version}", handleWithRouter(logger, readRouteTimeout, xRouter.AnimalVersion(dbSession, logger)), xChain.WithMeta(animalDocs.version))
+	api.Handle("POST /animal/{reqObjID}/revert/{version}", handleWithRouter(logger, defaultRouteTimeout, xRouter.RevertAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.revert))
+	api.Handle("GET /animal/:export", handleWithStream(logger, "animal", xRouter.ExportAnimals(dbSession, logger)), xChain.WithMeta(animalDocs.export))
+	api.Handle("GET /animal/:trash", handleWithRouter(logger, readRouteTimeout, xRouter.ListAnimalTrash(dbSession, logger)), xChain.WithMeta(animalDocs.trash))
+	api.Handle("POST /animal/{reqObjID}/restore", handleWithRouter(logger, defaultRouteTimeout, xRouter.RestoreAnimal(dbSession, logger)), xChain.WithMeta(animalDocs.restore))
+	api.Handle("POST /animal/:import", handleWithRouter(logger, bulkRouteTimeout, xRouter.ImportAnimals(dbSession, logger)), xChain.WithMeta(animalDocs.importing), xChain.Without("idempotency"))
+
+	// Batches run their operations through the same router functions as single requests
+	batchRoutes := xRouter.NewBatchRoutes()
+	batchRoutes.Handle("GET /person/{reqObjID}", xRouter.GetPerson(dbSession, logger))
+	batchRoutes.Handle("PUT /person/{reqObjID}", xRouter.UpdatePerson(dbSession, logger))
+	batchRoutes.Handle("POST /person/", xRouter.CreatePerson(dbSession, logger))
+	batchRoutes.Handle("PATCH /person/{reqObjID}", xRouter.PatchPerson(dbSession, logger))
+	batchRoutes.Handle("DELETE /person/{reqObjID}", xRouter.DeletePerson(dbSession, logger))
+	batchRoutes.Handle("GET /person/{reqObjID}/animals", xRouter.ListPersonAnimals(dbSession, logger))
+	batchRoutes.Handle("POST /person/{reqObjID}/animals/{animalID}", xRouter.AdoptAnimal(dbSession, logger))
+	batchRoutes.Handle("DELETE /person/{reqObjID}/animals/{animalID}", xRouter.ReleaseAnimal(dbSession, logger))
+	batchRoutes.Handle("POST /person/{reqObjID}/restore", xRouter.RestorePerson(dbSession, logger))
+	batchRoutes.Handle("GET /person/{reqObjID}/versions/{version}", xRouter.PersonVersion(dbSession, logger))
+	batchRoutes.Handle("POST /person/{reqObjID}/revert/{version}", xRouter.RevertPerson(dbSession, logger))
+	batchRoutes.Handle("GET /animal/{reqObjID}", xRouter.GetAnimal(dbSession, logger))
+	batchRoutes.Handle("PUT /animal/{reqObjID}", xRouter.UpdateAnimal(dbSession, logger))
+	batchRoutes.Handle("POST /animal/", xRouter.CreateAnimal(dbSession, logger))
+	batchRoutes.Handle("PATCH /animal/{reqObjID}", xRouter.PatchAnimal(dbSession, logger))
+	batchRoutes.Handle("DELETE /animal/{reqObjID}", xRouter.DeleteAnimal(dbSession, logger))
+	batchRoutes.Handle("POST /animal/{reqObjID}/restore", xRouter.RestoreAnimal(dbSession, logger))
+	batchRoutes.Handle("GET /animal/{reqObjID}/versions/{version}", xRouter.AnimalVersion(dbSession, logger))
+	batchRoutes.Handle("POST /animal/{reqObjID}/revert/{version}", xRouter.RevertAnimal(dbSession, logger))
+	api.Handle("POST /batch", handleWithRouter(logger, bulkRouteTimeout, xRouter.Batch(dbSession, logger, batchRoutes)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "batch",
+		Summary:     "Run an ordered list of person and animal operations, optionally in one transaction",
+		Tags:        []string{"batch"},
+		RequestBody: xRouter.BatchRequest{},
+		Query: []xOpenAPI.Param{{
+			Name:        "atomic",
+			Description: "true runs every operation in one transaction that is rolled back on the first failure",
+			Enum:        []string{"true", "false"},
+		}},
+		Responses: []xOpenAPI.Response{
+			{Status: http.StatusOK, Body: xRouter.BatchResponse{}},
+			{Status: http.StatusUnprocessableEntity, Description: "Atomic batch rolled back", Body: xRouter.BatchResponse{}},
+		},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusServiceUnavailable},
+		JSONOnly: true,
+	}))
+
+	api.Handle("POST /graphql", handleWithRouter(logger, defaultRouteTimeout, xGql.Execute(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "graphql",
+		Summary:     "Run a GraphQL query or mutation over persons, animals and their clone relations",
+		Tags:        []string{"graphql"},
+		RequestBody: xGql.Request{},
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: xGql.Response{}}},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusServiceUnavailable},
+		JSONOnly:    true,
+	}))
+
+	webhookTags := []string{"webhooks"}
+	webhookErrors := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable}
+	api.Handle("POST /webhooks", handleWithRouter(logger, defaultRouteTimeout, xRouter.CreateWebhook(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "create_webhook",
+		Summary:     "Subscribe a URL to change events, administrators only. The signing secret is generated when left out and only answered here",
+		Tags:        webhookTags,
+		RequestBody: xModels.Webhook{},
+		Responses:   []xOpenAPI.Response{{Status: http.StatusCreated, Body: xModels.Webhook{}}},
+		Errors:      append(slices.Clone(webhookErrors), http.StatusUnsupportedMediaType),
+	}))
+	api.Handle("GET /webhooks", handleWithRouter(logger, readRouteTimeout, xRouter.ListWebhooks(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "list_webhooks",
+		Summary:     "List the webhooks, administrators only",
+		Tags:        webhookTags,
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: []xModels.Webhook{}}},
+		Errors:      webhookErrors,
+	}))
+	api.Handle("GET /webhooks/{reqObjID}", handleWithRouter(logger, readRouteTimeout, xRouter.GetWebhook(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "get_webhook",
+		Summary:     "Get a webhook by ID, administrators only",
+		Tags:        webhookTags,
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: xModels.Webhook{}}},
+		Errors:      webhookErrors,
+	}))
+	api.Handle("PATCH /webhooks/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.PatchWebhook(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "patch_webhook",
+		Summary:     "Change the URL, filters or secret of a webhook, or pause it by setting Active to false, administrators only",
+		Tags:        webhookTags,
+		RequestBody: xModels.Webhook{},
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: xModels.Webhook{}}},
+		Errors:      append(slices.Clone(webhookErrors), http.StatusUnsupportedMediaType),
+	}))
+	api.Handle("DELETE /webhooks/{reqObjID}", handleWithRouter(logger, defaultRouteTimeout, xRouter.DeleteWebhook(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "delete_webhook",
+		Summary:     "Delete a webhook, administrators only",
+		Tags:        webhookTags,
+		Responses:   []xOpenAPI.Response{{Status: http.StatusOK}},
+		Errors:      webhookErrors,
+	}))
+	api.Handle("GET /webhooks/{reqObjID}/deliveries", handleWithRouter(logger, readRouteTimeout, xRouter.WebhookDeliveries(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "list_webhook_deliveries",
+		Summary:     "Page through the delivery log of a webhook in event order, administrators only",
+		Tags:        webhookTags,
+		Query: []xOpenAPI.Param{
+			{Name: "after", Description: "EventSeq of the last delivery of the previous page"},
+			{Name: "limit", Description: "Page size, 100 by default and at most 1000"},
+		},
+		Responses: []xOpenAPI.Response{{Status: http.StatusOK, Body: []xModels.WebhookDelivery{}}},
+		Errors:    webhookErrors,
+	}))
+	api.Handle("POST /webhooks/{reqObjID}/deliveries/{delivery}/redeliver", handleWithRouter(logger, defaultRouteTimeout, xRouter.RedeliverWebhook(dbSession, logger)), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "redeliver_webhook",
+		Summary:     "Send a delivery again with a fresh set of attempts, dead-lettered or not, administrators only",
+		Tags:        webhookTags,
+		Responses:   []xOpenAPI.Response{{Status: http.StatusAccepted, Body: xModels.WebhookDelivery{}}},
+		Errors:      webhookErrors,
+	}))
+
+	// A subscription lasts for hours, one trace would collect a database span every poll
+	api.Handle("GET /events", handleEvents(logger, dbSession), xChain.Without("tracing"), xChain.WithMeta(xOpenAPI.Operation{
+		OperationID: "events",
+		Summary:     "Stream changes to persons and animals as Server-Sent Events, send Last-Event-ID to resume after an event",
+		Tags:        []string{"events"},
+		Query: []xOpenAPI.Param{
+			{Name: "resource", Description: "Only changes to this resource", Enum: []string{"person", "animal"}},
+			{Name: "id", Description: "Only changes to the record with this ID"},
+			{Name: "kind", Description: "Only changes of this kind", Enum: xRouter.EventKinds},
+		},
+		Responses: []xOpenAPI.Response{{
+			Status:      http.StatusOK,
+			Description: "One event per change, its data is a ChangeEvent",
+			ContentType: "text/event-stream",
+			Body:        xRouter.ChangeEvent{},
+		}},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+	}))
+
+	api.Handle("GET /debug/routes", http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			api.Dump(w)
+		},
+	))
+
+	// Operational endpoints are scraped by infrastructure that holds no credentials
+	public := api.Group().Remove("auth", "idempotency", "access-log")
+	public.Handle("GET /healthz", handleHealth(dbSession))
+	public.Handle("GET /metrics", xMetrics.Handler(), xChain.Without("tracing"))
+
+	spec := sync.OnceValues(func() ([]byte, error) {
+		return xOpenAPI.Build(apiTitle, apiVersion, api.Routes())
+	})
+	public.Handle("GET /openapi.json", xOpenAPI.SpecHandler(spec))
+	public.Handle("GET /docs", xOpenAPI.DocsHandler(apiTitle, "/openapi.json", "/docs/assets"))
+	public.Handle("GET /docs/assets/", xOpenAPI.AssetsHandler("/docs/assets/"))
+
+	return api
+}
+
+type resourceDocs struct {
+	get    xOpenAPI.Operation
+	put    xOpenAPI.Operation
+	create xOpenAPI.Operation
+	patch  xOpenAPI.Operation
+	delete xOpenAPI.Operation
+	// importing documents the bulk import route, import being a keyword
+	importing xOpenAPI.Operation
+	export    xOpenAPI.Operation
+	history   xOpenAPI.Operation
+	trash     xOpenAPI.Operation
+	restore   xOpenAPI.Operation
+	version   xOpenAPI.Operation
+	revert    xOpenAPI.Operation
+}
+
+// docsFor documents the CRUD routes every resource shares
+func docsFor(resource string, model any) resourceDocs {
+	tags := []string{resource}
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(model)), 0, 0).Interface()
+	return resourceDocs{
+		get: xOpenAPI.Operation{
+			OperationID: "get_" + resource,
+			Summary:     "Get a " + resource + " by ID",
+			Tags:        tags,
+			Query: []xOpenAPI.Param{{
+				Name:        "as_of",
+				Description: "RFC 3339 timestamp, answers with the " + resource + " as it was at that time",
+			}},
+			Responses: []xOpenAPI.Response{{Status: http.StatusOK, Body: model}},
+			Errors:    []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		put: xOpenAPI.Operation{
+			OperationID: "put_" + resource,
+			Summary:     "Replace a " + resource + ", creating it when the ID is unknown",
+			Tags:        tags,
+			RequestBody: model,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusNoContent}},
+			// 409 when the ID belongs to a record in the trash
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotAcceptable, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		create: xOpenAPI.Operation{
+			OperationID: "create_" + resource,
+			Summary:     "Create a " + resource + " with a generated ID",
+			Tags:        tags,
+			RequestBody: model,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusCreated}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotAcceptable, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		patch: xOpenAPI.Operation{
+			OperationID: "patch_" + resource,
+			Summary:     "Change some fields of an existing " + resource,
+			Tags:        tags,
+			RequestBody: model,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusNoContent}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		delete: xOpenAPI.Operation{
+			OperationID: "delete_" + resource,
+			Summary:     "Move a " + resource + " to the trash",
+			Tags:        tags,
+			Query: []xOpenAPI.Param{{
+				Name:        "force",
+				Description: "true deletes the " + resource + " permanently, administrators only",
+				Enum:        []string{"true", "false"},
+			}},
+			Responses: []xOpenAPI.Response{{Status: http.StatusOK}},
+			// 409 when the restrict rules refuse the deletion of an origin with clones or of a person owning animals
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusNotAcceptable, http.StatusConflict, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		importing: xOpenAPI.Operation{
+			OperationID:       "import_" + resource,
+			Summary:           "Bulk create " + resource + " records from NDJSON or CSV, reporting the outcome of every line",
+			Tags:              tags,
+			RequestBody:       list,
+			RequestMediaTypes: []string{xCodec.NDJSON{}.MediaType(), xCodec.CSV{}.MediaType()},
+			Query: []xOpenAPI.Param{{
+				Name:        "mode",
+				Description: "atomic rolls back the whole import on the first bad record, best-effort keeps every valid record",
+				Enum:        []string{xRouter.ImportModeAtomic, xRouter.ImportModeBestEffort},
+			}},
+			Responses: []xOpenAPI.Response{
+				{Status: http.StatusOK, Body: xRouter.ImportReport{}},
+				{Status: http.StatusUnprocessableEntity, Description: "Atomic import rolled back", Body: xRouter.ImportReport{}},
+			},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotAcceptable, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		export: xOpenAPI.Operation{
+			OperationID: "export_" + resource,
+			Summary:     "Stream every " + resource + " matching the filters as an attachment",
+			Tags:        tags,
+			Query:       filterParams(model),
+			Responses: []xOpenAPI.Response{{
+				Status:     http.StatusOK,
+				Body:       list,
+				MediaTypes: []string{xCodec.NDJSON{}.MediaType(), xCodec.CSV{}.MediaType(), xCodec.JSON{}.MediaType()},
+			}},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotAcceptable},
+		},
+		history: xOpenAPI.Operation{
+			OperationID: "history_" + resource,
+			Summary:     "List every change made to a " + resource + ", oldest first",
+			Tags:        tags,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: []xRouter.HistoryEntry{}}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		version: xOpenAPI.Operation{
+			OperationID: "version_" + resource,
+			Summary:     "Get a " + resource + " as version n left it, versions are numbered from 1",
+			Tags:        tags,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: model}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		revert: xOpenAPI.Operation{
+			OperationID: "revert_" + resource,
+			Summary:     "Write version n of a " + resource + " back as a new version",
+			Tags:        tags,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusNoContent}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusConflict, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		trash: xOpenAPI.Operation{
+			OperationID: "trash_" + resource,
+			Summary:     "List the deleted " + resource + " records still waiting to be purged",
+			Tags:        tags,
+			Query: []xOpenAPI.Param{
+				{Name: "after", Description: "ID of the last record of the previous page"},
+				{Name: "limit", Description: "Page size, 100 by default and at most 1000"},
+			},
+			Responses: []xOpenAPI.Response{{Status: http.StatusOK, Body: list}},
+			Errors:    []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		restore: xOpenAPI.Operation{
+			OperationID: "restore_" + resource,
+			Summary:     "Bring a deleted " + resource + " back from the trash",
+			Tags:        tags,
+			Responses:   []xOpenAPI.Response{{Status: http.StatusOK, Body: model}},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+	}
+}
+
+// filterParams documents the column filters accepted by list routes, see xSession.Conditions
+func filterParams(model any) []xOpenAPI.Param {
+	params := []xOpenAPI.Param{}
+	for _, column := range xSession.Columns(model) {
+		params = append(params, xOpenAPI.Param{Name: column, Description: "Only records whose " + column + " equals this value"})
+	}
+	for _, filter := range xSession.SinceFilters(model) {
+		params = append(params, xOpenAPI.Param{Name: filter.Name, Description: "Only records whose " + filter.Column + " is at or after this RFC 3339 timestamp"})
+	}
+	return params
+}
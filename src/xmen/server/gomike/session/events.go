@@ -0,0 +1,35 @@
+package session
+
+import (
+	"strings"
+
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// The change feed is read from the audit trail: entries are numbered by Seq when they are sealed, after their
+// changes committed. Pending entries have no Seq yet and join the feed once sealed, after every entry before them.
+
+// EventsAfter returns up to limit sealed audit entries matching conditions with a Seq greater than afterSeq, in Seq order
+func EventsAfter(dbSession *xDb.DBSession, conditions map[string]interface{}, afterSeq int64, limit int) ([]xModels.AuditEntry, error) {
+	entries := []xModels.AuditEntry{}
+	err := dbSession.ListRecordsAfter(conditions, "seq", afterSeq, limit, &entries)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return entries, nil
+}
+
+// LatestEventSeq returns the Seq of the newest audit entry, 0 when there is none
+func LatestEventSeq(dbSession *xDb.DBSession) (int64, error) {
+	entry := xModels.AuditEntry{}
+	err := dbSession.ReadFirstRecord(map[string]interface{}{}, "seq DESC", &entry)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			return 0, nil
+		}
+		return 0, xError.NewDBError(err)
+	}
+	return entry.Seq, nil
+}
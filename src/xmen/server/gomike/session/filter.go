@@ -0,0 +1,140 @@
+package session
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	xError "gomike/error"
+	xDb "lib/dbchef"
+)
+
+// Conditions turns query parameters named after the columns of T into equality conditions, and
+// <name>_since parameters into lower bounds on the <name>_at time columns. Parameters listed in reserved
+// are left out
+func Conditions[T Storable](query url.Values, reserved ...string) (map[string]interface{}, error) {
+	columns := map[string]reflect.Kind{}
+	since := map[string]string{}
+	for _, c := range columnsOf(reflect.TypeOf(new(T)).Elem()) {
+		if c.time {
+			if filter, ok := sinceFilter(c.name); ok {
+				since[filter] = c.name
+			}
+			continue
+		}
+		columns[c.name] = c.kind
+	}
+
+	conditions := map[string]interface{}{}
+	for name, values := range query {
+		if slices.Contains(reserved, name) {
+			continue
+		}
+		value := values[len(values)-1]
+		if column, ok := since[name]; ok {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, xError.NewValidationError(fmt.Errorf("filter %s: %q is not an RFC 3339 timestamp", name, value))
+			}
+			conditions[column] = xDb.Since(t)
+			continue
+		}
+		kind, ok := columns[name]
+		if !ok {
+			return nil, xError.NewValidationError(fmt.Errorf("unknown filter %q", name))
+		}
+		switch kind {
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, xError.NewValidationError(fmt.Errorf("filter %s: %q is not a boolean", name, value))
+			}
+			conditions[name] = b
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, xError.NewValidationError(fmt.Errorf("filter %s: %q is not an integer", name, value))
+			}
+			conditions[name] = n
+		default:
+			conditions[name] = value
+		}
+	}
+	return conditions, nil
+}
+
+// Columns lists the columns of model that can be filtered on for equality, in field order
+func Columns(model any) []string {
+	names := []string{}
+	for _, c := range columnsOf(reflect.TypeOf(model)) {
+		if !c.time {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// SinceFilter is a filter keeping the records whose Column holds a time at or after its value
+type SinceFilter struct {
+	Name   string
+	Column string
+}
+
+// SinceFilters lists the <name>_since filters accepted for model, in field order
+func SinceFilters(model any) []SinceFilter {
+	filters := []SinceFilter{}
+	for _, c := range columnsOf(reflect.TypeOf(model)) {
+		if filter, ok := sinceFilter(c.name); ok && c.time {
+			filters = append(filters, SinceFilter{Name: filter, Column: c.name})
+		}
+	}
+	return filters
+}
+
+func sinceFilter(column string) (string, bool) {
+	prefix, ok := strings.CutSuffix(column, "_at")
+	if !ok {
+		return "", false
+	}
+	return prefix + "_since", true
+}
+
+type column struct {
+	name string
+	kind reflect.Kind
+	time bool
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func columnsOf(t reflect.Type) []column {
+	columns := []column{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, columnsOf(field.Type)...)
+			continue
+		}
+		name := GormSettings(field.Tag.Get("gorm"))["column"]
+		if name == "" {
+			continue
+		}
+		if field.Type == timeType {
+			columns = append(columns, column{name: name, kind: reflect.Struct, time: true})
+			continue
+		}
+		// Only scalar columns can be matched against a query parameter
+		if field.Type.Kind() == reflect.Struct {
+			continue
+		}
+		columns = append(columns, column{name: name, kind: field.Type.Kind()})
+	}
+	return columns
+}
@@ -0,0 +1,19 @@
+package session
+
+import (
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// History returns the audit entries of one record in chain order. Entries written before the chain all have
+// Seq 0 and fall back to timestamp order, as do the newest ones still waiting to be sealed, which come last.
+func History(dbSession *xDb.DBSession, resource string, recordID string) ([]xModels.AuditEntry, error) {
+	entries := []xModels.AuditEntry{}
+	conditions := map[string]interface{}{"resource": resource, "record_id": recordID}
+	err := dbSession.FindRecords(conditions, "pending, seq, timestamp, id", &entries)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return entries, nil
+}
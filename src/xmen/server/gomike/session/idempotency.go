@@ -0,0 +1,18 @@
+package session
+
+import (
+	"time"
+
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// DeleteExpiredIdempotencyKeys removes the idempotency keys whose TTL ran out before now
+func DeleteExpiredIdempotencyKeys(dbSession *xDb.DBSession, now time.Time) (int64, error) {
+	deleted, err := dbSession.DeleteRecordsBefore(&xModels.IdempotencyKey{}, "expires_at", now)
+	if err != nil {
+		return 0, xError.NewDBError(err)
+	}
+	return deleted, nil
+}
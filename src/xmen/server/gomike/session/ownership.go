@@ -0,0 +1,121 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// What deleting a person does to the animals it owns
+const (
+	OwnerDeleteRestrict = "restrict"
+	OwnerDeleteCascade  = "cascade"
+)
+
+// ErrOwnerHasAnimals refuses the deletion of a person who still owns animals under the restrict rule
+var ErrOwnerHasAnimals = errors.New("person still owns animals")
+
+// OwnerDeleteRuleFromEnv reads GOMIKE_OWNER_DELETE_RULE, restrict (the default) or cascade
+func OwnerDeleteRuleFromEnv() (string, error) {
+	rule := os.Getenv("GOMIKE_OWNER_DELETE_RULE")
+	switch rule {
+	case "":
+		return OwnerDeleteRestrict, nil
+	case OwnerDeleteRestrict, OwnerDeleteCascade:
+		return rule, nil
+	}
+	return "", fmt.Errorf("invalid GOMIKE_OWNER_DELETE_RULE %q, expected restrict or cascade", rule)
+}
+
+// EnforceOwnerDeleteRule applies rule whenever a person is deleted or purged, whichever API does it. Under
+// restrict the deletion fails while the person owns live animals and a purge disowns those in the trash,
+// under cascade the animals go with the person.
+func EnforceOwnerDeleteRule(rule string) {
+	xDb.AddMutationHook(xModels.Person{}, func(tx *xDb.DBSession, mutation xDb.Mutation) error {
+		if mutation.Operation != "delete" && mutation.Operation != "purge" {
+			return nil
+		}
+		conditions := map[string]interface{}{"owner_id": fmt.Sprint(mutation.Key)}
+		animals := []xModels.Animal{}
+		if err := tx.FindRecords(conditions, "id", &animals); err != nil {
+			return err
+		}
+		if rule == OwnerDeleteRestrict {
+			if len(animals) > 0 {
+				return fmt.Errorf("%w: %d animals", ErrOwnerHasAnimals, len(animals))
+			}
+			if mutation.Operation == "purge" {
+				// Animals in the trash still name the person, restoring one must not bring back a dangling owner
+				return disownTrashed(tx, conditions)
+			}
+			return nil
+		}
+
+		if mutation.Operation == "delete" {
+			for i := range animals {
+				if err := tx.DeleteRecord(&animals[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// Purging also takes the animals that were already in the trash
+		animals = []xModels.Animal{}
+		if err := tx.Unscoped().FindRecords(conditions, "id", &animals); err != nil {
+			return err
+		}
+		for i := range animals {
+			if err := tx.PurgeRecord(&animals[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// disownTrashed leaves the trashed animals matching conditions without an owner
+func disownTrashed(tx *xDb.DBSession, conditions map[string]interface{}) error {
+	trashed := []xModels.Animal{}
+	if err := tx.Unscoped().FindRecords(conditions, "id", &trashed); err != nil {
+		return err
+	}
+	for i := range trashed {
+		trashed[i].OwnerID = ""
+		if err := tx.Unscoped().UpdateRecordColumns(&trashed[i], "owner_id", "updated_at", "updated_by"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OwnedAnimals returns up to limit animals owned by personID in id order, starting after afterID
+func OwnedAnimals(dbSession *xDb.DBSession, personID string, afterID string, limit int) ([]xModels.Animal, error) {
+	return ListRecords[xModels.Animal](dbSession, map[string]interface{}{"owner_id": personID}, afterID, limit)
+}
+
+// SetOwner makes personID the owner of animalID, or leaves the animal without an owner when personID is
+// empty. The person row stays locked until the change commits, so it cannot be deleted meanwhile.
+func SetOwner(dbSession *xDb.DBSession, animalID string, personID string) (*xModels.Animal, error) {
+	animal := &xModels.Animal{}
+	err := dbSession.Transaction(func(tx *xDb.DBSession) error {
+		if personID != "" {
+			person := xModels.Person{}
+			if err := tx.ReadRecordForUpdate(map[string]interface{}{"id": personID}, &person); err != nil {
+				return fmt.Errorf("person %s: %w", personID, err)
+			}
+		}
+		if err := tx.ReadRecord(map[string]interface{}{"id": animalID}, animal); err != nil {
+			return fmt.Errorf("animal %s: %w", animalID, err)
+		}
+		animal.OwnerID = personID
+		return tx.UpdateRecordColumns(animal, "owner_id", "updated_at", "updated_by")
+	})
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return animal, nil
+}
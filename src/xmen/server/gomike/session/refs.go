@@ -0,0 +1,276 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	xError "gomike/error"
+	xDb "lib/dbchef"
+
+	"gorm.io/gorm"
+)
+
+// What deleting an origin does to the records cloned from it
+const (
+	CloneDeleteRestrict = "restrict"
+	CloneDeleteOrphan   = "orphan"
+	CloneDeleteCascade  = "cascade"
+)
+
+var (
+	// ErrInvalidCloneRef refuses a ClonedFromRef pointing at itself, at a clone of itself or at no record of the same kind
+	ErrInvalidCloneRef = errors.New("invalid cloned_from_ref")
+	// ErrOriginHasClones refuses the deletion of an origin that still has clones under the restrict rule
+	ErrOriginHasClones = errors.New("record still has clones")
+)
+
+// maxCloneDepth bounds the walk up the clone chain looking for cycles
+const maxCloneDepth = 1000
+
+// CloneDeleteRuleFromEnv reads GOMIKE_CLONE_DELETE_RULE, restrict (the default), orphan or cascade
+func CloneDeleteRuleFromEnv() (string, error) {
+	rule := os.Getenv("GOMIKE_CLONE_DELETE_RULE")
+	switch rule {
+	case "":
+		return CloneDeleteRestrict, nil
+	case CloneDeleteRestrict, CloneDeleteOrphan, CloneDeleteCascade:
+		return rule, nil
+	}
+	return "", fmt.Errorf("invalid GOMIKE_CLONE_DELETE_RULE %q, expected restrict, orphan or cascade", rule)
+}
+
+// EnforceCloneRefs checks the ClonedFromRef of every T written and applies rule when a T is deleted or purged.
+// Under restrict an origin with live clones cannot be deleted, under cascade its clones go with it. Under orphan,
+// and for the clones left when an origin is purged from the trash, the clones keep Cloned but lose ClonedFromRef.
+// While an origin is in the trash its clones keep pointing at it, so restoring it brings the relation back.
+func EnforceCloneRefs[T Storable](resource string, rule string) {
+	xDb.AddMutationHook(new(T), func(tx *xDb.DBSession, mutation xDb.Mutation) error {
+		switch mutation.Operation {
+		case "create", "update":
+			id, ref := cloneRef(mutation.After)
+			_, before := cloneRef(mutation.Before)
+			if ref == "" || ref == before {
+				return nil
+			}
+			return checkCloneRef[T](tx, resource, id, ref)
+		case "delete":
+			return deleteClones[T](tx, resource, fmt.Sprint(mutation.Key), rule)
+		case "purge":
+			return purgeClones[T](tx, resource, fmt.Sprint(mutation.Key), rule, inTrash(mutation.Before))
+		}
+		return nil
+	})
+}
+
+// checkCloneRef makes sure ref names another live T whose own chain of origins does not lead back to id.
+// The origin row stays locked until the change commits, so a concurrent delete cannot miss the new clone.
+func checkCloneRef[T Storable](tx *xDb.DBSession, resource string, id string, ref string) error {
+	if ref == id {
+		return fmt.Errorf("%w: %s %s cannot be cloned from itself", ErrInvalidCloneRef, resource, id)
+	}
+	origin := new(T)
+	if err := tx.ReadRecordForUpdate(map[string]interface{}{"id": ref}, origin); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: no %s with ID %s", ErrInvalidCloneRef, resource, ref)
+		}
+		return err
+	}
+	for depth := 0; depth < maxCloneDepth; depth++ {
+		_, next := cloneRef(origin)
+		if next == "" {
+			return nil
+		}
+		if next == id {
+			return fmt.Errorf("%w: %s %s is already a clone of %s %s", ErrInvalidCloneRef, resource, ref, resource, id)
+		}
+		origin = new(T)
+		if err := tx.Unscoped().ReadRecord(map[string]interface{}{"id": next}, origin); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteClones[T Storable](tx *xDb.DBSession, resource string, id string, rule string) error {
+	if rule == CloneDeleteOrphan {
+		return nil
+	}
+	clones := []T{}
+	if err := tx.FindRecords(map[string]interface{}{"cloned_from_ref": id}, "id", &clones); err != nil {
+		return err
+	}
+	if rule == CloneDeleteRestrict {
+		if len(clones) > 0 {
+			return fmt.Errorf("%w: %d %s records are cloned from %s", ErrOriginHasClones, len(clones), resource, id)
+		}
+		return nil
+	}
+	for i := range clones {
+		if err := tx.DeleteRecord(&clones[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeClones applies rule to the clones of a purged origin. An origin purged from the trash already went
+// through the rule when it was deleted, the clones it has left are orphaned.
+func purgeClones[T Storable](tx *xDb.DBSession, resource string, id string, rule string, wasInTrash bool) error {
+	clones := []T{}
+	if err := tx.Unscoped().FindRecords(map[string]interface{}{"cloned_from_ref": id}, "id", &clones); err != nil {
+		return err
+	}
+	if rule == CloneDeleteRestrict && !wasInTrash {
+		live := 0
+		for i := range clones {
+			if !inTrash(&clones[i]) {
+				live++
+			}
+		}
+		if live > 0 {
+			return fmt.Errorf("%w: %d %s records are cloned from %s", ErrOriginHasClones, live, resource, id)
+		}
+	}
+	for i := range clones {
+		var err error
+		if rule == CloneDeleteCascade && !wasInTrash {
+			err = tx.PurgeRecord(&clones[i])
+		} else {
+			err = orphan(tx, &clones[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orphan clears the ClonedFromRef of record, trashed or not, leaving Cloned set
+func orphan(tx *xDb.DBSession, record any) error {
+	reflect.ValueOf(record).Elem().FieldByName("ClonedFromRef").SetString("")
+	return tx.Unscoped().UpdateRecordColumns(record, "cloned_from_ref", "updated_at", "updated_by")
+}
+
+// cloneRef returns the ID and ClonedFromRef of a record or a pointer to one, empty strings for nil
+func cloneRef(record any) (string, string) {
+	if record == nil {
+		return "", ""
+	}
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return "", ""
+	}
+	return value.FieldByName("ID").String(), value.FieldByName("ClonedFromRef").String()
+}
+
+// inTrash reports whether record, or the record a pointer leads to, is soft-deleted
+func inTrash(record any) bool {
+	if record == nil {
+		return false
+	}
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return false
+	}
+	deletedAt, ok := value.FieldByName("DeletedAt").Interface().(gorm.DeletedAt)
+	return ok && deletedAt.Valid
+}
+
+// BrokenRef is a ClonedFromRef found pointing at nothing usable
+type BrokenRef struct {
+	Resource string
+	ID       string
+	Ref      string
+	Problem  string
+}
+
+// BrokenCloneRefs lists the T records, trashed ones included, whose ClonedFromRef names themselves or no T.
+// Refs naming an Other record instead are told apart, Other being the other kind of record.
+func BrokenCloneRefs[T Storable, Other Storable](dbSession *xDb.DBSession, resource string, other string) ([]BrokenRef, error) {
+	all := dbSession.Unscoped()
+	broken := []BrokenRef{}
+	pending := map[string][]string{}
+	check := func() error {
+		refs := make([]string, 0, len(pending))
+		for ref := range pending {
+			refs = append(refs, ref)
+		}
+		found, err := existingIDs[T](all, refs)
+		if err != nil {
+			return err
+		}
+		misfiled, err := existingIDs[Other](all, refs)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			if found[ref] {
+				continue
+			}
+			problem := "no " + resource + " has this ID"
+			if misfiled[ref] {
+				problem = "points at a " + other
+			}
+			for _, id := range pending[ref] {
+				broken = append(broken, BrokenRef{Resource: resource, ID: id, Ref: ref, Problem: problem})
+			}
+		}
+		pending = map[string][]string{}
+		return nil
+	}
+
+	err := StreamRecords[T](all, nil, func(record T) error {
+		id, ref := cloneRef(record)
+		switch {
+		case ref == "":
+		case ref == id:
+			broken = append(broken, BrokenRef{Resource: resource, ID: id, Ref: ref, Problem: "points at itself"})
+		default:
+			pending[ref] = append(pending[ref], id)
+			if len(pending) >= 500 {
+				return check()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := check(); err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return broken, nil
+}
+
+func existingIDs[T Storable](dbSession *xDb.DBSession, ids []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	records := []T{}
+	if err := dbSession.FindRecords(map[string]interface{}{"id": ids}, "id", &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		id, _ := cloneRef(record)
+		found[id] = true
+	}
+	return found, nil
+}
+
+// RepairCloneRef orphans the T with id, clearing a broken ClonedFromRef and leaving Cloned set
+func RepairCloneRef[T Storable](dbSession *xDb.DBSession, id string) error {
+	record := new(T)
+	if err := dbSession.Unscoped().ReadRecord(map[string]interface{}{"id": id}, record); err != nil {
+		return xError.NewDBError(err)
+	}
+	if err := orphan(dbSession, record); err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
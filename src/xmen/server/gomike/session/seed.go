@@ -8,11 +8,20 @@ import (
 	xDb "lib/dbchef"
 )
 
-// SeedTables creates and seeds Person and Animal tables
+// SeedTables creates and seeds the Person and Animal tables and the tables gomike keeps for itself
 func SeedTables(dbSession *xDb.DBSession) error {
 	models := []interface{}{
 		&xModels.Person{},
 		&xModels.Animal{},
+		&xModels.IdempotencyKey{},
+		&xModels.AuditEntry{},
+		&xModels.AuditHead{},
+		&xModels.AuditCheckpoint{},
+		&xModels.RecordVersion{},
+		&xModels.Webhook{},
+		&xModels.WebhookDelivery{},
+		&xModels.WebhookCursor{},
+		&xModels.OutboxCursor{},
 	}
 
 	err := dbSession.SeedTables(models)
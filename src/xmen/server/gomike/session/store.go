@@ -3,6 +3,8 @@
 package session
 
 import (
+	"reflect"
+
 	xError "gomike/error"
 	xDb "lib/dbchef"
 )
@@ -17,6 +19,22 @@ func CreateRecord[T Storable](dbSession *xDb.DBSession, obj T) error {
 	return nil
 }
 
+func CreateRecords[T Storable](dbSession *xDb.DBSession, objs []T, batchSize int) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	err := dbSession.CreateRecords(&objs, batchSize)
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
+
+// Transaction runs fn in a database transaction, rolling back when it returns an error
+func Transaction(dbSession *xDb.DBSession, fn func(tx *xDb.DBSession) error) error {
+	return dbSession.Transaction(fn)
+}
+
 func ReadRecord[T Storable](dbSession *xDb.DBSession, objID string) (*T, error) {
 	obj := new(T)
 	err := dbSession.ReadRecord(map[string]interface{}{"id": objID}, obj)
@@ -26,8 +44,29 @@ func ReadRecord[T Storable](dbSession *xDb.DBSession, objID string) (*T, error)
 	return obj, nil
 }
 
+func ListRecords[T Storable](dbSession *xDb.DBSession, conditions map[string]interface{}, afterID string, limit int) ([]T, error) {
+	objs := []T{}
+	err := dbSession.ListRecords(conditions, afterID, limit, &objs)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return objs, nil
+}
+
+// StreamRecords hands every record matching conditions to fn in id order without loading them all at once
+func StreamRecords[T Storable](dbSession *xDb.DBSession, conditions map[string]interface{}, fn func(T) error) error {
+	obj := new(T)
+	err := dbSession.StreamRecords(conditions, "id", obj, func() error { return fn(*obj) })
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
+
+// UpdateRecord writes every column of obj a client can set, zero values included, so a field cleared by the
+// caller is cleared in the database too
 func UpdateRecord[T Storable](dbSession *xDb.DBSession, obj T) error {
-	err := dbSession.UpdateRecord(&obj)
+	err := dbSession.UpdateRecordColumns(&obj, WritableColumns(obj)...)
 	if err != nil {
 		return xError.NewDBError(err)
 	}
@@ -41,3 +80,33 @@ func DeleteRecord[T Storable](dbSession *xDb.DBSession, obj T) error {
 	}
 	return nil
 }
+
+// WritableColumns lists the columns of model a client can set, in field order: the primary key and the fields
+// tagged readonly:"true" are left out, updated_at and updated_by are kept so writes are stamped
+func WritableColumns(model any) []string {
+	return writableColumns(reflect.Indirect(reflect.ValueOf(model)).Type())
+}
+
+func writableColumns(t reflect.Type) []string {
+	columns := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, writableColumns(field.Type)...)
+			continue
+		}
+		settings := GormSettings(field.Tag.Get("gorm"))
+		name := settings["column"]
+		if _, primaryKey := settings["primarykey"]; name == "" || primaryKey {
+			continue
+		}
+		if field.Tag.Get("readonly") == "true" && name != "updated_at" && name != "updated_by" {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	xError "gomike/error"
+	xDb "lib/dbchef"
+
+	"gorm.io/gorm"
+)
+
+// ListDeletedRecords lists the soft-deleted records of T in id order, starting after afterID
+func ListDeletedRecords[T Storable](dbSession *xDb.DBSession, afterID string, limit int) ([]T, error) {
+	objs := []T{}
+	err := dbSession.ListDeletedRecords(nil, afterID, limit, &objs)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return objs, nil
+}
+
+// InTrash reports whether the record of T with objID was soft-deleted and is waiting to be purged
+func InTrash[T Storable](dbSession *xDb.DBSession, objID string) (bool, error) {
+	obj := new(T)
+	err := dbSession.Unscoped().ReadRecord(map[string]interface{}{"id": objID}, obj)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, xError.NewDBError(err)
+	}
+	return inTrash(obj), nil
+}
+
+// RestoreRecord takes the record with objID out of the trash
+func RestoreRecord[T Storable](dbSession *xDb.DBSession, objID string) (*T, error) {
+	obj := new(T)
+	err := dbSession.Unscoped().ReadRecord(map[string]interface{}{"id": objID}, obj)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	err = dbSession.RestoreRecord(obj)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return obj, nil
+}
+
+// PurgeRecord hard-deletes the record with objID, whether it is in the trash or not
+func PurgeRecord[T Storable](dbSession *xDb.DBSession, objID string) error {
+	obj := new(T)
+	err := dbSession.Unscoped().ReadRecord(map[string]interface{}{"id": objID}, obj)
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	err = dbSession.PurgeRecord(obj)
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
+
+// PurgeDeletedBefore hard-deletes the records of T that went to the trash before cutoff. Records that could not
+// be purged are skipped, they come back as xDb.PurgeFailure errors so the caller can report each one.
+func PurgeDeletedBefore[T Storable](dbSession *xDb.DBSession, cutoff time.Time) (int64, error) {
+	purged, err := dbSession.PurgeDeletedBefore(new(T), cutoff)
+	var failure *xDb.PurgeFailure
+	if err != nil && !errors.As(err, &failure) {
+		return purged, xError.NewDBError(err)
+	}
+	return purged, err
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	xError "gomike/error"
+)
+
+var varcharLength = regexp.MustCompile(`^(?i)(?:varchar|character varying|char)\((\d+)\)$`)
+
+// Validate checks a record against the constraints declared in its gorm tags, so bad rows are
+// reported before they reach the database
+func Validate(obj any) error {
+	value := reflect.Indirect(reflect.ValueOf(obj))
+	if value.Kind() != reflect.Struct {
+		return xError.NewValidationError(fmt.Errorf("expected a record, got %s", value.Kind()))
+	}
+	problems := validateFields(value)
+	if len(problems) > 0 {
+		return xError.NewValidationError(fmt.Errorf("%s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+func validateFields(value reflect.Value) []string {
+	problems := []string{}
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			problems = append(problems, validateFields(value.Field(i))...)
+			continue
+		}
+		settings := GormSettings(field.Tag.Get("gorm"))
+		fieldValue := value.Field(i)
+		if _, notNull := settings["not null"]; notNull && field.Type.Kind() == reflect.String && fieldValue.String() == "" {
+			problems = append(problems, fmt.Sprintf("%s is required", field.Name))
+		}
+		if match := varcharLength.FindStringSubmatch(settings["type"]); match != nil && field.Type.Kind() == reflect.String {
+			limit, _ := strconv.Atoi(match[1])
+			if utf8.RuneCountInString(fieldValue.String()) > limit {
+				problems = append(problems, fmt.Sprintf("%s exceeds %d characters", field.Name, limit))
+			}
+		}
+	}
+	return problems
+}
+
+// GormSettings splits a gorm struct tag into lower-cased keys, e.g. "column:id;primaryKey" gives column and primarykey
+func GormSettings(tag string) map[string]string {
+	settings := map[string]string{}
+	for _, part := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if key = strings.TrimSpace(key); key != "" {
+			settings[strings.ToLower(key)] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
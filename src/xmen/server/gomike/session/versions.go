@@ -0,0 +1,31 @@
+package session
+
+import (
+	"time"
+
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// Version returns version n of one record
+func Version(dbSession *xDb.DBSession, resource string, recordID string, n int64) (*xModels.RecordVersion, error) {
+	version := &xModels.RecordVersion{}
+	conditions := map[string]interface{}{"resource": resource, "record_id": recordID, "version": n}
+	err := dbSession.ReadRecord(conditions, version)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return version, nil
+}
+
+// VersionAsOf returns the version of one record that was current at asOf
+func VersionAsOf(dbSession *xDb.DBSession, resource string, recordID string, asOf time.Time) (*xModels.RecordVersion, error) {
+	version := &xModels.RecordVersion{}
+	conditions := map[string]interface{}{"resource": resource, "record_id": recordID}
+	err := dbSession.ReadFirstRecordUntil(conditions, "valid_from", asOf, "version DESC", version)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return version, nil
+}
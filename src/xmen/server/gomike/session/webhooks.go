@@ -0,0 +1,50 @@
+package session
+
+import (
+	"time"
+
+	xError "gomike/error"
+	xModels "gomike/models"
+	xDb "lib/dbchef"
+)
+
+// DueDeliveries returns up to limit pending webhook deliveries whose next attempt is due at now, most overdue first
+func DueDeliveries(dbSession *xDb.DBSession, now time.Time, limit int) ([]xModels.WebhookDelivery, error) {
+	deliveries := []xModels.WebhookDelivery{}
+	conditions := map[string]interface{}{"status": xModels.DeliveryPending}
+	err := dbSession.ListRecordsUntil(conditions, "next_attempt_at", now, limit, &deliveries)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return deliveries, nil
+}
+
+// Deliveries returns up to limit deliveries of one webhook in event order, starting after the event afterSeq
+func Deliveries(dbSession *xDb.DBSession, webhookID string, afterSeq int64, limit int) ([]xModels.WebhookDelivery, error) {
+	deliveries := []xModels.WebhookDelivery{}
+	conditions := map[string]interface{}{"webhook_id": webhookID}
+	err := dbSession.ListRecordsAfter(conditions, "event_seq", afterSeq, limit, &deliveries)
+	if err != nil {
+		return nil, xError.NewDBError(err)
+	}
+	return deliveries, nil
+}
+
+// RequeuePendingDeliveries makes the pending deliveries of a webhook due at now, they were pushed back while it was paused
+func RequeuePendingDeliveries(dbSession *xDb.DBSession, webhookID string, now time.Time) error {
+	conditions := map[string]interface{}{"webhook_id": webhookID, "status": xModels.DeliveryPending}
+	_, err := dbSession.UpdateRecordsWhere(&xModels.WebhookDelivery{}, conditions, map[string]interface{}{"next_attempt_at": now})
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
+
+// UpdateColumns writes only the named columns of obj, zero values included
+func UpdateColumns[T Storable](dbSession *xDb.DBSession, obj T, columns ...string) error {
+	err := dbSession.UpdateRecordColumns(&obj, columns...)
+	if err != nil {
+		return xError.NewDBError(err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package tracing
+
+import (
+	xDb "lib/dbchef"
+)
+
+// ObserveDBQuery records a dbchef operation as a child span, it is meant to be installed with xDb.AddQueryObserver
+func ObserveDBQuery(event xDb.QueryEvent) {
+	if SpanFromContext(event.Context) == nil {
+		return
+	}
+	_, span := StartAt(event.Context, "dbchef."+event.Operation, SpanKindClient, event.Start)
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.operation", event.Operation)
+	span.SetAttribute("db.model", event.Model)
+	span.SetAttribute("db.rows_affected", event.Rows)
+	span.RecordError(event.Err)
+	span.FinishAt(event.Start.Add(event.Duration))
+}
@@ -0,0 +1,287 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter ships finished spans to a backend
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+const (
+	batchSize     = 512
+	queueSize     = 2048
+	flushInterval = 5 * time.Second
+)
+
+type batcher struct {
+	exporter Exporter
+	queue    chan *Span
+	flush    chan chan struct{}
+	onError  func(error)
+}
+
+var (
+	activeMu sync.RWMutex
+	active   *batcher
+)
+
+// SetExporter installs the exporter that receives finished spans in batches
+func SetExporter(exporter Exporter, onError func(error)) {
+	b := &batcher{
+		exporter: exporter,
+		queue:    make(chan *Span, queueSize),
+		flush:    make(chan chan struct{}),
+		onError:  onError,
+	}
+	go b.run()
+	activeMu.Lock()
+	active = b
+	activeMu.Unlock()
+}
+
+// Flush blocks until all queued spans have been handed to the exporter or ctx is done
+func Flush(ctx context.Context) error {
+	activeMu.RLock()
+	b := active
+	activeMu.RUnlock()
+	if b == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case b.flush <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func export(span *Span) {
+	activeMu.RLock()
+	b := active
+	activeMu.RUnlock()
+	if b == nil {
+		return
+	}
+	select {
+	case b.queue <- span:
+	default:
+		// Drop the span rather than block the request when the exporter falls behind
+	}
+}
+
+func (b *batcher) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	pending := make([]*Span, 0, batchSize)
+	send := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if err := b.exporter.Export(pending); err != nil && b.onError != nil {
+			b.onError(err)
+		}
+		pending = make([]*Span, 0, batchSize)
+	}
+	for {
+		select {
+		case span := <-b.queue:
+			pending = append(pending, span)
+			if len(pending) >= batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-b.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-b.queue:
+					pending = append(pending, span)
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(done)
+		}
+	}
+}
+
+// WriterExporter writes spans as JSON lines, suitable for stdout, files and offline tests
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+type spanRecord struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         SpanKind       `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	DurationMs   float64        `json:"duration_ms"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+func (e *WriterExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		record := spanRecord{
+			TraceID:    span.SpanContext.TraceID.String(),
+			SpanID:     span.SpanContext.SpanID.String(),
+			Name:       span.Name,
+			Kind:       span.Kind,
+			Start:      span.Start,
+			End:        span.End,
+			DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+			Attributes: span.Attributes,
+		}
+		if span.ParentSpanID.IsValid() {
+			record.ParentSpanID = span.ParentSpanID.String()
+		}
+		if span.Err != nil {
+			record.Error = span.Err.Error()
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector using the JSON encoding
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *OTLPExporter) Export(spans []*Span) error {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		otlpSpan := map[string]any{
+			"traceId":           span.SpanContext.TraceID.String(),
+			"spanId":            span.SpanContext.SpanID.String(),
+			"name":              span.Name,
+			"kind":              int(span.Kind),
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+		}
+		if span.ParentSpanID.IsValid() {
+			otlpSpan["parentSpanId"] = span.ParentSpanID.String()
+		}
+		if span.Err != nil {
+			otlpSpan["status"] = map[string]any{"code": 2, "message": span.Err.Error()}
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": e.serviceName}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "gomike/tracing"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export to %s failed with status %d", e.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	out := make([]map[string]any, 0, len(attributes))
+	for key, value := range attributes {
+		var otlpValue map[string]any
+		switch v := value.(type) {
+		case bool:
+			otlpValue = map[string]any{"boolValue": v}
+		case int:
+			otlpValue = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			otlpValue = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			otlpValue = map[string]any{"doubleValue": v}
+		default:
+			otlpValue = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]any{"key": key, "value": otlpValue})
+	}
+	return out
+}
+
+// NewExporterFromEnv builds an exporter from OTEL_TRACES_EXPORTER (otlp, console, file or none),
+// OTEL_EXPORTER_OTLP_ENDPOINT and GOMIKE_TRACES_FILE. It returns nil when tracing export is disabled.
+func NewExporterFromEnv(serviceName string) (Exporter, error) {
+	switch kind := os.Getenv("OTEL_TRACES_EXPORTER"); kind {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "http://localhost:4318"
+		}
+		return NewOTLPExporter(endpoint, serviceName), nil
+	case "console":
+		return NewWriterExporter(os.Stdout), nil
+	case "file":
+		path := os.Getenv("GOMIKE_TRACES_FILE")
+		if path == "" {
+			path = "gomike-traces.jsonl"
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return NewWriterExporter(file), nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", kind)
+	}
+}
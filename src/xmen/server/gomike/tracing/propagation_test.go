@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"surrounding space", " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ", true, true},
+		{"later version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"empty", "", false, false},
+		{"missing field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"short trace ID", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false, false},
+		{"short span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", false, false},
+		{"non-hex trace ID", "00-4bf92f3577b34da6a3ce929d0e0e47z-00f067aa0ba902b7-01", false, false},
+		{"non-hex flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", false, false},
+		{"all-zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"all-zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(c.value)
+			if ok != c.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %v, want %v", c.value, ok, c.ok)
+			}
+			if !ok {
+				if sc != (SpanContext{}) {
+					t.Errorf("rejected value returned %+v, want the zero span context", sc)
+				}
+				return
+			}
+			if got := sc.TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("trace ID = %s", got)
+			}
+			if got := sc.SpanID.String(); got != "00f067aa0ba902b7" {
+				t.Errorf("span ID = %s", got)
+			}
+			if sc.Sampled != c.sampled {
+				t.Errorf("sampled = %v, want %v", sc.Sampled, c.sampled)
+			}
+		})
+	}
+}
+
+func TestInjectRoundTrip(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: sampled}
+		header := http.Header{}
+		Inject(sc, header)
+
+		req := &http.Request{Header: header}
+		got, ok := Extract(req)
+		if !ok || got != sc {
+			t.Errorf("Extract(Inject(%+v)) = %+v, %v", sc, got, ok)
+		}
+	}
+}
+
+func TestInjectSkipsInvalidContext(t *testing.T) {
+	header := http.Header{}
+	Inject(SpanContext{}, header)
+	if value := header.Get(TraceparentHeader); value != "" {
+		t.Errorf("traceparent = %q for an invalid span context, want none", value)
+	}
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestChildSpanInheritsTrace(t *testing.T) {
+	var out bytes.Buffer
+	SetExporter(NewWriterExporter(&out), func(err error) { t.Errorf("export: %v", err) })
+
+	ctx, parent := Start(context.Background(), "request", SpanKindServer)
+	_, child := Start(ctx, "router", SpanKindInternal)
+	child.SetAttribute("http.route", "GET /person/{reqObjID}")
+	child.Finish()
+	parent.Finish()
+	if err := Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	spans := map[string]spanRecord{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var record spanRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("decoding exported span: %v", err)
+		}
+		spans[record.Name] = record
+	}
+	gotParent, gotChild := spans["request"], spans["router"]
+	if gotParent.SpanID == "" || gotChild.SpanID == "" {
+		t.Fatalf("exported spans %+v, want request and router", spans)
+	}
+	if gotChild.TraceID != gotParent.TraceID || gotChild.TraceID != parent.SpanContext.TraceID.String() {
+		t.Errorf("child trace ID %s, parent %s, want the same", gotChild.TraceID, gotParent.TraceID)
+	}
+	if gotChild.ParentSpanID != gotParent.SpanID {
+		t.Errorf("child parent span ID %s, want %s", gotChild.ParentSpanID, gotParent.SpanID)
+	}
+	if gotChild.SpanID == gotParent.SpanID {
+		t.Errorf("child reuses the span ID %s of its parent", gotChild.SpanID)
+	}
+	if gotParent.ParentSpanID != "" {
+		t.Errorf("root span has parent %s", gotParent.ParentSpanID)
+	}
+	if gotChild.Attributes["http.route"] != "GET /person/{reqObjID}" {
+		t.Errorf("child attributes %v", gotChild.Attributes)
+	}
+}
+
+func TestChildOfRemoteParent(t *testing.T) {
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := Start(ContextWithRemoteSpanContext(context.Background(), remote), "request", SpanKindServer)
+
+	if span.SpanContext.TraceID != remote.TraceID || span.ParentSpanID != remote.SpanID {
+		t.Errorf("span %+v parent %s, want trace %s and parent %s", span.SpanContext, span.ParentSpanID, remote.TraceID, remote.SpanID)
+	}
+	if span.SpanContext.Sampled {
+		t.Errorf("span is sampled though its remote parent is not")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	xModels "gomike/models"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashRetentionFromEnv reads GOMIKE_TRASH_RETENTION, how long deleted records stay restorable, e.g. 720h
+func trashRetentionFromEnv() (time.Duration, error) {
+	raw := os.Getenv("GOMIKE_TRASH_RETENTION")
+	if raw == "" {
+		return defaultTrashRetention, nil
+	}
+	retention, err := time.ParseDuration(raw)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("invalid GOMIKE_TRASH_RETENTION %q", raw)
+	}
+	return retention, nil
+}
+
+// purgeTrash hard-deletes the records that have been in the trash for longer than retention
+func purgeTrash(log *slog.Logger, dbSession *xDb.DBSession, retention time.Duration) {
+	ticker := time.NewTicker(min(retention, time.Hour))
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().Add(-retention)
+		purgeTrashOf[xModels.Person](log, dbSession, "person", cutoff)
+		purgeTrashOf[xModels.Animal](log, dbSession, "animal", cutoff)
+	}
+}
+
+func purgeTrashOf[T any](log *slog.Logger, dbSession *xDb.DBSession, resource string, cutoff time.Time) {
+	purged, err := xSession.PurgeDeletedBefore[T](dbSession.WithContext(context.Background()), cutoff)
+	if err != nil {
+		failures := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			failures = joined.Unwrap()
+		}
+		// One line per record left in the trash, each failure names its record
+		for _, failure := range failures {
+			log.Error("Failed to purge trash", slog.String("resource", resource), slog.String("error", failure.Error()))
+		}
+	}
+	if purged > 0 {
+		log.Info("Purged trash", slog.String("resource", resource), slog.Int64("purged", purged))
+	}
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	xModels "gomike/models"
+	xOutbound "gomike/outbound"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is dead-lettered
+	MaxAttempts     = 8
+	firstRetryDelay = 10 * time.Second
+	maxRetryDelay   = time.Hour
+	sendTimeout     = 10 * time.Second
+	sendWorkers     = 8
+)
+
+// RetryDelay is how long to wait after the given number of failed attempts, doubling from 10s up to an hour
+func RetryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+type sender struct {
+	log       *slog.Logger
+	dbSession *xDb.DBSession
+	client    *http.Client
+	// save records the outcome of an attempt
+	save func(delivery xModels.WebhookDelivery) error
+}
+
+func newSender(log *slog.Logger, dbSession *xDb.DBSession) *sender {
+	return &sender{
+		log:       log,
+		dbSession: dbSession,
+		client:    &http.Client{Timeout: sendTimeout},
+		save: func(delivery xModels.WebhookDelivery) error {
+			return xSession.UpdateColumns(dbSession, delivery, "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at")
+		},
+	}
+}
+
+// sendDue pushes the deliveries that are due, a few at a time so one slow receiver does not hold up the others
+func (s *sender) sendDue() {
+	deliveries, err := xSession.DueDeliveries(s.dbSession, time.Now().UTC(), batchSize)
+	if err != nil {
+		s.log.Error("Failed to read due webhook deliveries", slog.String("error", err.Error()))
+		return
+	}
+	hooks := map[string]*xModels.Webhook{}
+	for _, delivery := range deliveries {
+		if _, ok := hooks[delivery.WebhookID]; ok {
+			continue
+		}
+		hook, err := xSession.ReadRecord[xModels.Webhook](s.dbSession, delivery.WebhookID)
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "record not found") {
+			s.log.Error("Failed to read webhook", slog.String("webhook", delivery.WebhookID), slog.String("error", err.Error()))
+			return
+		}
+		hooks[delivery.WebhookID] = hook
+	}
+
+	work := make(chan xModels.WebhookDelivery)
+	var wg sync.WaitGroup
+	for range sendWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range work {
+				s.attempt(hooks[delivery.WebhookID], delivery)
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		work <- delivery
+	}
+	close(work)
+	wg.Wait()
+}
+
+// attempt sends delivery to hook once and records the outcome, hook is nil when the webhook was deleted
+func (s *sender) attempt(hook *xModels.Webhook, delivery xModels.WebhookDelivery) {
+	now := time.Now().UTC()
+	switch {
+	case hook == nil:
+		delivery.Status = xModels.DeliveryDead
+		delivery.LastError = "webhook no longer exists"
+	case !hook.Active:
+		// Deliveries of a paused webhook wait without using up their attempts, resuming it makes them due again
+		delivery.NextAttemptAt = now.Add(maxRetryDelay)
+	default:
+		delivery.Attempts++
+		statusCode, err := s.send(hook, delivery)
+		delivery.LastStatusCode = statusCode
+		if err == nil {
+			delivery.Status = xModels.DeliveryDelivered
+			delivery.LastError = ""
+			delivery.DeliveredAt = &now
+			break
+		}
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= MaxAttempts {
+			delivery.Status = xModels.DeliveryDead
+			s.log.Warn("Webhook delivery dead-lettered", slog.String("webhook", hook.ID), slog.String("delivery", delivery.ID), slog.String("error", err.Error()))
+		} else {
+			delivery.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts))
+		}
+	}
+
+	if err := s.save(delivery); err != nil {
+		s.log.Error("Failed to record webhook delivery", slog.String("delivery", delivery.ID), slog.String("error", err.Error()))
+	}
+}
+
+// send posts the payload of delivery to hook, any answer but a 2xx is a failure
+func (s *sender) send(hook *xModels.Webhook, delivery xModels.WebhookDelivery) (int, error) {
+	// The request ID of the change travels on, so the receiver's logs can be matched with gomike's
+	ctx, cancel := context.WithTimeout(xRouter.WithRequestID(context.Background(), delivery.RequestID), sendTimeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := xOutbound.NewRequest(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+	req.Header.Set(EventHeader, delivery.Kind)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Draining a little of the body lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
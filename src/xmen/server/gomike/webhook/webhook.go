@@ -0,0 +1,158 @@
+// Outgoing webhooks: change events are fanned out to the subscriptions they match and pushed with retries
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	xModels "gomike/models"
+	xRouter "gomike/router"
+	xSession "gomike/session"
+	xDb "lib/dbchef"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every delivery. The signature is "sha256=" followed by the hex HMAC-SHA256, keyed by the
+// webhook secret, of the timestamp header, a dot and the body.
+const (
+	SignatureHeader = "X-Gomike-Signature"
+	TimestampHeader = "X-Gomike-Timestamp"
+	EventHeader     = "X-Gomike-Event"
+	DeliveryHeader  = "X-Gomike-Delivery"
+)
+
+const (
+	cursorID     = 1
+	pollInterval = time.Second
+	batchSize    = 100
+)
+
+// Sign returns the signature header value of a delivery body sent at timestamp
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Matches reports whether event passes the resource and kind filters of hook
+func Matches(hook xModels.Webhook, event xRouter.ChangeEvent) bool {
+	return inList(hook.Resources, event.Resource) && inList(hook.Kinds, event.Kind)
+}
+
+// inList reports whether value is in the comma-separated list, an empty list holds every value
+func inList(list string, value string) bool {
+	if strings.TrimSpace(list) == "" {
+		return true
+	}
+	return slices.ContainsFunc(strings.Split(list, ","), func(item string) bool {
+		return strings.TrimSpace(item) == value
+	})
+}
+
+// EnsureCursor creates the row recording how far the audit trail was fanned out, webhooks only hear about
+// changes made after the first start
+func EnsureCursor(dbSession *xDb.DBSession) error {
+	cursor := xModels.WebhookCursor{}
+	err := dbSession.ReadRecord(map[string]interface{}{"id": cursorID}, &cursor)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(strings.ToLower(err.Error()), "record not found") {
+		return err
+	}
+	seq, err := xSession.LatestEventSeq(dbSession)
+	if err != nil {
+		return err
+	}
+	return dbSession.CreateRecord(&xModels.WebhookCursor{ID: cursorID, Seq: seq})
+}
+
+// Run fans new changes out to the webhooks and pushes the deliveries that are due, until the process exits
+func Run(log *slog.Logger, dbSession *xDb.DBSession) {
+	sender := newSender(log, dbSession)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for {
+			queued, err := fanOut(dbSession)
+			if err != nil {
+				log.Error("Failed to queue webhook deliveries", slog.String("error", err.Error()))
+				break
+			}
+			if queued < batchSize {
+				break
+			}
+		}
+		sender.sendDue()
+	}
+}
+
+// fanOut turns the next batch of sealed audit entries into deliveries for the active webhooks they match and moves
+// the cursor past them, in one transaction. It returns how many entries it read.
+func fanOut(dbSession *xDb.DBSession) (int, error) {
+	read := 0
+	err := dbSession.Transaction(func(tx *xDb.DBSession) error {
+		cursor := xModels.WebhookCursor{}
+		if err := tx.ReadRecordForUpdate(map[string]interface{}{"id": cursorID}, &cursor); err != nil {
+			return fmt.Errorf("webhook cursor: %w", err)
+		}
+		entries, err := xSession.EventsAfter(tx, nil, cursor.Seq, batchSize)
+		if err != nil {
+			return err
+		}
+		read = len(entries)
+		if read == 0 {
+			return nil
+		}
+		hooks := []xModels.Webhook{}
+		if err := tx.FindRecords(map[string]interface{}{"active": true}, "id", &hooks); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, entry := range entries {
+			event, err := xRouter.NewChangeEvent(entry)
+			if err != nil {
+				// A corrupt entry is reported by audit verify, it must not hold the other events back
+				continue
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			for _, hook := range hooks {
+				if !Matches(hook, event) {
+					continue
+				}
+				err := tx.CreateRecord(&xModels.WebhookDelivery{
+					ID:            uuid.New().String(),
+					WebhookID:     hook.ID,
+					EventSeq:      event.Seq,
+					Kind:          event.Kind,
+					RequestID:     event.RequestID,
+					Payload:       string(payload),
+					Status:        xModels.DeliveryPending,
+					NextAttemptAt: now,
+					CreatedAt:     now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		cursor.Seq = entries[len(entries)-1].Seq
+		return tx.UpdateRecordColumns(&cursor, "seq")
+	})
+	return read, err
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	xModels "gomike/models"
+)
+
+// receiver is a local webhook endpoint answering with the queued status codes, 200 once they run out
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	answered []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.answered = append(r.answered, status)
+	w.WriteHeader(status)
+}
+
+// newTestSender returns a sender posting to a local receiver and the slice its saved deliveries land in
+func newTestSender(t *testing.T, rcv *receiver) (*sender, *xModels.Webhook, *[]xModels.WebhookDelivery) {
+	t.Helper()
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+	saved := &[]xModels.WebhookDelivery{}
+	s := &sender{
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		client: server.Client(),
+		save: func(delivery xModels.WebhookDelivery) error {
+			*saved = append(*saved, delivery)
+			return nil
+		},
+	}
+	hook := &xModels.Webhook{ID: "hook-1", URL: server.URL, Secret: "s3cret", Active: true}
+	return s, hook, saved
+}
+
+func newDelivery() xModels.WebhookDelivery {
+	return xModels.WebhookDelivery{
+		ID:            "delivery-1",
+		WebhookID:     "hook-1",
+		EventSeq:      42,
+		Kind:          "update",
+		RequestID:     "req-1",
+		Payload:       `{"seq":42,"kind":"update"}`,
+		Status:        xModels.DeliveryPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	rcv := &receiver{}
+	s, hook, saved := newTestSender(t, rcv)
+
+	s.attempt(hook, newDelivery())
+
+	if len(rcv.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rcv.requests))
+	}
+	req, body := rcv.requests[0], rcv.bodies[0]
+	timestamp := req.Header.Get(TimestampHeader)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("timestamp header %q is not a unix time: %v", timestamp, err)
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign(hook.Secret, timestamp, body); got != want {
+		t.Errorf("signature header = %q, want %q", got, want)
+	}
+	if got := req.Header.Get(SignatureHeader); got == Sign("other secret", timestamp, body) {
+		t.Errorf("signature %q does not depend on the secret", got)
+	}
+	if got := req.Header.Get(EventHeader); got != "update" {
+		t.Errorf("event header = %q, want update", got)
+	}
+	if got := req.Header.Get(DeliveryHeader); got != "delivery-1" {
+		t.Errorf("delivery header = %q, want delivery-1", got)
+	}
+	if string(body) != newDelivery().Payload {
+		t.Errorf("body = %s, want the payload", body)
+	}
+
+	if len(*saved) != 1 || (*saved)[0].Status != xModels.DeliveryDelivered || (*saved)[0].DeliveredAt == nil {
+		t.Errorf("saved %+v, want one delivered delivery", *saved)
+	}
+}
+
+func TestSignKnownValue(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac key
+	const want = "sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae"
+	if got := Sign("key", "1700000000", []byte("{}")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if Sign("key", "1700000001", []byte("{}")) == want {
+		t.Errorf("Sign does not cover the timestamp")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 80 * time.Second},
+		{7, 640 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+	for _, c := range cases {
+		if got := RetryDelay(c.attempts); got != c.want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestFailedAttemptIsRetriedLater(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	s, hook, saved := newTestSender(t, rcv)
+
+	delivery := newDelivery()
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now().UTC()
+		s.attempt(hook, delivery)
+		delivery = (*saved)[len(*saved)-1]
+
+		if delivery.Status != xModels.DeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("after attempt %d: status %s, attempts %d", attempt, delivery.Status, delivery.Attempts)
+		}
+		wait := delivery.NextAttemptAt.Sub(before)
+		if wait < RetryDelay(attempt) || wait > RetryDelay(attempt)+time.Second {
+			t.Errorf("after attempt %d the next one is due in %s, want %s", attempt, wait, RetryDelay(attempt))
+		}
+		if delivery.LastStatusCode != rcv.answered[attempt-1] || delivery.LastError == "" {
+			t.Errorf("after attempt %d: last status %d, last error %q", attempt, delivery.LastStatusCode, delivery.LastError)
+		}
+	}
+}
+
+func TestDeadLetterAndRedeliver(t *testing.T) {
+	failures := make([]int, MaxAttempts)
+	for i := range failures {
+		failures[i] = http.StatusServiceUnavailable
+	}
+	rcv := &receiver{statuses: failures}
+	s, hook, saved := newTestSender(t, rcv)
+
+	delivery := newDelivery()
+	for range MaxAttempts {
+		if delivery.Status != xModels.DeliveryPending {
+			t.Fatalf("delivery %s after %d attempts, want pending until %d", delivery.Status, delivery.Attempts, MaxAttempts)
+		}
+		s.attempt(hook, delivery)
+		delivery = (*saved)[len(*saved)-1]
+	}
+	if delivery.Status != xModels.DeliveryDead || delivery.Attempts != MaxAttempts {
+		t.Fatalf("after %d failures: status %s, attempts %d, want dead", MaxAttempts, delivery.Status, delivery.Attempts)
+	}
+
+	// Redelivering from the delivery log starts over and reaches the receiver, now healthy
+	delivery.Requeue(time.Now().UTC())
+	if delivery.Status != xModels.DeliveryPending || delivery.Attempts != 0 {
+		t.Fatalf("requeued delivery: status %s, attempts %d", delivery.Status, delivery.Attempts)
+	}
+	s.attempt(hook, delivery)
+	delivery = (*saved)[len(*saved)-1]
+	if delivery.Status != xModels.DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("redelivered: status %s, attempts %d, want delivered after 1", delivery.Status, delivery.Attempts)
+	}
+	if len(rcv.requests) != MaxAttempts+1 {
+		t.Errorf("receiver got %d requests, want %d", len(rcv.requests), MaxAttempts+1)
+	}
+}
+
+func TestDeletedWebhookIsDeadLettered(t *testing.T) {
+	rcv := &receiver{}
+	s, _, saved := newTestSender(t, rcv)
+
+	s.attempt(nil, newDelivery())
+
+	if len(rcv.requests) != 0 {
+		t.Errorf("receiver got %d requests for a deleted webhook", len(rcv.requests))
+	}
+	if len(*saved) != 1 || (*saved)[0].Status != xModels.DeliveryDead || (*saved)[0].Attempts != 0 {
+		t.Errorf("saved %+v, want one dead delivery without attempts", *saved)
+	}
+}